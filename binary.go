@@ -0,0 +1,108 @@
+package logx
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+)
+
+type BinaryEncoding uint8
+
+const (
+	// the standard base64 encoding
+	BinaryBase64 BinaryEncoding = iota
+	// the lowercase hex encoding
+	BinaryHex
+	// a multi-line block in the format of "hexdump -C" following the entry
+	// written by the console encoder, the nested fields and the json encoder
+	// fall back to BinaryHex
+	BinaryHexDump
+)
+
+type BinaryOption struct {
+	// binary encoding, default: BinaryBase64
+	Encoding BinaryEncoding
+	// max bytes of the Binary and ByteString fields, 0 means no limit
+	MaxBytes int
+}
+
+// hexDump is a Binary field dumped after the entry by the console encoder.
+type hexDump struct {
+	key   string
+	value []byte
+}
+
+func (enc *JsonEncoder) maxBinaryBytes(value []byte) ([]byte, int) {
+	if limit := enc.binary.MaxBytes; limit > 0 && len(value) > limit {
+		return value[:limit], len(value) - limit
+	}
+	return value, 0
+}
+
+// writeFieldBinary writes the binary value, the top-level values with a key are
+// dumped after the entry by the console encoder with BinaryHexDump.
+func (enc *JsonEncoder) writeFieldBinary(key string, value []byte) {
+	if enc.binary.Encoding == BinaryHexDump && enc.console && enc.depth == 1 && len(key) > 0 {
+		enc.hexDumps = append(enc.hexDumps, hexDump{key: key, value: value})
+		enc.writeQuote()
+		enc.beginColor(enc.colors.attr.StringColor)
+		enc.buf.AppendString("hexdump ")
+		enc.buf.AppendInt(int64(len(value)))
+		enc.buf.AppendString(" bytes")
+		enc.endColor()
+		enc.writeQuote()
+		return
+	}
+	data, truncated := enc.maxBinaryBytes(value)
+	enc.writeQuote()
+	enc.beginColor(enc.colors.attr.StringColor)
+	if enc.binary.Encoding == BinaryBase64 {
+		enc.buf.bs = base64.StdEncoding.AppendEncode(enc.buf.bs, data)
+	} else {
+		enc.buf.bs = hex.AppendEncode(enc.buf.bs, data)
+	}
+	if truncated > 0 {
+		appendTruncatedMarker(enc.buf, truncated, "bytes")
+	}
+	enc.endColor()
+	enc.writeQuote()
+}
+
+func (enc *JsonEncoder) writeFieldByteString(value string) {
+	if limit := enc.binary.MaxBytes; limit > 0 && len(value) > limit {
+		var truncated int
+		value, truncated = truncateString(value, limit)
+		if enc.redactor != nil && len(enc.redactor.patterns) > 0 {
+			value = enc.redactor.replace(value)
+		}
+		enc.writeQuote()
+		enc.beginColor(enc.colors.attr.StringColor)
+		enc.writeRawString(value)
+		appendTruncatedMarker(enc.buf, truncated, "bytes")
+		enc.endColor()
+		enc.writeQuote()
+		return
+	}
+	enc.writeFieldString(value)
+}
+
+// appendHexDumps appends the hex dumps on the lines following the entry.
+func appendHexDumps(buf *Buffer, dumps []hexDump, maxBytes int) {
+	for _, dump := range dumps {
+		value, truncated := dump.value, 0
+		if maxBytes > 0 && len(value) > maxBytes {
+			value, truncated = value[:maxBytes], len(value)-maxBytes
+		}
+		buf.AppendByte('\n')
+		buf.AppendString(dump.key)
+		buf.AppendByte(':')
+		buf.AppendByte('\n')
+		dumper := hex.Dumper(buf)
+		dumper.Write(value)
+		dumper.Close()
+		if truncated > 0 {
+			appendTruncatedMarker(buf, truncated, "bytes")
+			buf.AppendByte('\n')
+		}
+		buf.TrimNewline()
+	}
+}
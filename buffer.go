@@ -68,6 +68,9 @@ func (b *Buffer) TryGrow(size int) { b.bs = slices.Grow(b.bs, size) }
 // backing array.
 func (b *Buffer) Reset() { b.bs = b.bs[:0] }
 
+// Truncate discards all but the first n bytes of the buffer.
+func (b *Buffer) Truncate(n int) { b.bs = b.bs[:n] }
+
 // Len returns the length of the underlying byte slice.
 func (b *Buffer) Len() int { return len(b.bs) }
 
@@ -2,7 +2,6 @@ package logx
 
 import (
 	"runtime"
-	"strconv"
 	"strings"
 )
 
@@ -42,31 +41,43 @@ type callerField struct {
 }
 
 func (c *callerField) AppendField(enc *JsonEncoder) {
-	fileName, funcName := c.value()
-	fields := [2]Field{}
-	var n int
-	if len(fileName) > 0 {
-		fields[0] = String(c.option.FileKey, fileName)
-		n++
+	file, line, funcName, ok := c.value()
+	enc.writeKey(c.option.CallerKey)
+	enc.writeBeginObject()
+	if ok {
+		enc.writeKey(c.option.FileKey)
+		enc.writeQuote()
+		enc.beginColor(enc.colors.attr.StringColor)
+		enc.writeRawString(file)
+		enc.buf.AppendByte(':')
+		enc.buf.AppendInt(int64(line))
+		enc.endColor()
+		enc.writeQuote()
 	}
 	if len(funcName) > 0 {
-		if n == 0 {
-			fields[0] = String(c.option.FuncKey, funcName)
-		} else {
-			fields[1] = String(c.option.FuncKey, funcName)
-		}
-		n++
+		enc.writeKey(c.option.FuncKey)
+		enc.writeFieldString(funcName)
 	}
-	enc.writeFieldKey(c.option.CallerKey)
-	enc.writeSplitColon()
-	enc.writeFieldObject(fields[:n])
+	enc.writeEndObject()
 }
 
-func (c *callerField) value() (fileName, funcName string) {
-	pc, file, line, ok := runtime.Caller(c.skipDepth)
-	if !ok {
+// value returns the caller without concatenating the file and line, so that
+// it doesn't allocate.
+func (c *callerField) value() (file string, line int, funcName string, ok bool) {
+	// runtime.Caller and runtime.CallersFrames allocate, look up the function of
+	// the program counter instead
+	var pcs [1]uintptr
+	if runtime.Callers(c.skipDepth+1, pcs[:]) == 0 {
 		return
 	}
+	// the program counter is a return address, so back up to the call instruction
+	pc := pcs[0] - 1
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return
+	}
+	file, line = fn.FileLine(pc)
+	ok = true
 	if c.option.Formatter == ShortFile || c.option.Formatter == ShortFileFunc {
 		if idx := strings.LastIndexByte(file, '/'); idx != -1 {
 			if idx = strings.LastIndexByte(file[:idx], '/'); idx != -1 {
@@ -74,10 +85,9 @@ func (c *callerField) value() (fileName, funcName string) {
 			}
 		}
 	}
-	fileName = file + ":" + strconv.FormatInt(int64(line), 10)
 
 	if c.option.Formatter == ShortFileFunc || c.option.Formatter == FullFileFunc {
-		funcName = runtime.FuncForPC(pc).Name()
+		funcName = fn.Name()
 		if idx := strings.LastIndexByte(funcName, '/'); idx != -1 {
 			funcName = funcName[idx+1:]
 		}
@@ -86,10 +96,17 @@ func (c *callerField) value() (fileName, funcName string) {
 }
 
 func (c *callerField) AppendPrimitive(buf *Buffer) {
-	fileName, _ := c.value()
-	if c.color {
-		appendColor(buf, YellowAttr, fileName)
+	file, line, _, ok := c.value()
+	if !ok {
 		return
 	}
-	buf.AppendString(fileName)
+	if c.color {
+		appendColorBegin(buf, YellowAttr)
+	}
+	buf.AppendString(file)
+	buf.AppendByte(':')
+	buf.AppendInt(int64(line))
+	if c.color {
+		appendColorEnd(buf)
+	}
 }
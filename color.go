@@ -90,17 +90,15 @@ func (c *colorfulset) init() {
 }
 
 func appendColor(buf *Buffer, color ColorAttr, s string) {
-	buf.AppendString(format)
-	color.appendTo(buf)
-	buf.AppendByte('m')
+	appendColorBegin(buf, color)
 	buf.AppendString(s)
-	buf.AppendString(unformat)
+	appendColorEnd(buf)
 }
 
-func appendColorWithFunc(buf *Buffer, color ColorAttr, append func(*Buffer)) {
+func appendColorBegin(buf *Buffer, color ColorAttr) {
 	buf.AppendString(format)
 	color.appendTo(buf)
 	buf.AppendByte('m')
-	append(buf)
-	buf.AppendString(unformat)
 }
+
+func appendColorEnd(buf *Buffer) { buf.AppendString(unformat) }
@@ -0,0 +1,346 @@
+package logx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+)
+
+// Config is the JSON representation of a LogContext. Omitted fields leave the
+// corresponding settings of the LogContext unchanged.
+//
+//	{
+//	  "level": "info",
+//	  "encoder": "json",
+//	  "outputs": ["stdout", "/var/log/app.log"],
+//	  "level_key": {"enable": true, "lower": true},
+//	  "time": {"enable": true, "layout": "2006-01-02T15:04:05Z07:00"},
+//	  "caller": {"enable": true, "formatter": "short_file_func"},
+//	  "sampling": {"enable": true, "tick": "1s", "first": 100, "thereafter": 10}
+//	}
+type Config struct {
+	// log level name, e.g. "debug"
+	Level string `json:"level,omitempty"`
+	// "console" or "json"
+	Encoder string `json:"encoder,omitempty"`
+	// "stdout", "stderr" or file paths opened in append mode
+	Outputs      []string `json:"outputs,omitempty"`
+	MsgKey       string   `json:"msg_key,omitempty"`
+	Color        *bool    `json:"color,omitempty"`
+	EscapeQuote  *bool    `json:"escape_quote,omitempty"`
+	Development  *bool    `json:"development,omitempty"`
+	ReflectValue *bool    `json:"reflect_value,omitempty"`
+	SortMapKeys  *bool    `json:"sort_map_keys,omitempty"`
+	// "string", "ns", "ms" or "s"
+	DurationEncoding string          `json:"duration_encoding,omitempty"`
+	LevelKey         *LevelConfig    `json:"level_key,omitempty"`
+	Time             *TimeConfig     `json:"time,omitempty"`
+	Caller           *CallerConfig   `json:"caller,omitempty"`
+	Sampling         *SamplingConfig `json:"sampling,omitempty"`
+
+	level       LevelType
+	encoder     EncoderType
+	durationEnc DurationEncoding
+}
+
+type LevelConfig struct {
+	Enable bool   `json:"enable"`
+	Key    string `json:"key,omitempty"`
+	Lower  bool   `json:"lower,omitempty"`
+}
+
+type TimeConfig struct {
+	Enable    bool   `json:"enable"`
+	Key       string `json:"key,omitempty"`
+	Layout    string `json:"layout,omitempty"`
+	Timestamp bool   `json:"timestamp,omitempty"`
+	// "layout", "rfc3339", "rfc3339nano", "epoch_s", "epoch_ms", "epoch_us" or "epoch_ns"
+	Encoding string `json:"encoding,omitempty"`
+	Float    bool   `json:"float,omitempty"`
+	UTC      bool   `json:"utc,omitempty"`
+
+	encoding TimeEncoding
+}
+
+type CallerConfig struct {
+	Enable  bool   `json:"enable"`
+	Key     string `json:"key,omitempty"`
+	FileKey string `json:"file_key,omitempty"`
+	FuncKey string `json:"func_key,omitempty"`
+	// "short_file", "full_file", "short_file_func" or "full_file_func"
+	Formatter string `json:"formatter,omitempty"`
+	Skip      int    `json:"skip,omitempty"`
+
+	formatter CallerFormatter
+}
+
+type SamplingConfig struct {
+	Enable bool `json:"enable"`
+	// duration string such as "1s"
+	Tick       string `json:"tick,omitempty"`
+	First      int    `json:"first,omitempty"`
+	Thereafter int    `json:"thereafter,omitempty"`
+
+	tick time.Duration
+}
+
+var callerFormatterNames = map[string]CallerFormatter{
+	"short_file":      ShortFile,
+	"full_file":       FullFile,
+	"short_file_func": ShortFileFunc,
+	"full_file_func":  FullFileFunc,
+}
+
+var timeEncodingNames = map[string]TimeEncoding{
+	"layout":      TimeLayout,
+	"rfc3339":     TimeRFC3339,
+	"rfc3339nano": TimeRFC3339Nano,
+	"epoch_s":     TimeEpochSeconds,
+	"epoch_ms":    TimeEpochMillis,
+	"epoch_us":    TimeEpochMicros,
+	"epoch_ns":    TimeEpochNanos,
+}
+
+var durationEncodingNames = map[string]DurationEncoding{
+	"string": DurationString,
+	"ns":     DurationNanos,
+	"ms":     DurationMillis,
+	"s":      DurationSeconds,
+}
+
+// LoadConfig reads and validates the JSON config file at path.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(data)
+}
+
+// ParseConfig parses and validates a JSON config.
+func ParseConfig(data []byte) (*Config, error) {
+	cfg := new(Config)
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("logx: invalid config: %w", err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (cfg *Config) validate() (err error) {
+	if len(cfg.Level) > 0 {
+		if cfg.level, err = ParseLevel(cfg.Level); err != nil {
+			return err
+		}
+	}
+	switch cfg.Encoder {
+	case "":
+	case "console":
+		cfg.encoder = Console
+	case "json":
+		cfg.encoder = Json
+	default:
+		return fmt.Errorf("logx: unknown encoder %q", cfg.Encoder)
+	}
+	for _, output := range cfg.Outputs {
+		if len(output) == 0 {
+			return errors.New("logx: empty output")
+		}
+	}
+	if cfg.Caller != nil && len(cfg.Caller.Formatter) > 0 {
+		formatter, ok := callerFormatterNames[cfg.Caller.Formatter]
+		if !ok {
+			return fmt.Errorf("logx: unknown caller formatter %q", cfg.Caller.Formatter)
+		}
+		cfg.Caller.formatter = formatter
+	}
+	if len(cfg.DurationEncoding) > 0 {
+		encoding, ok := durationEncodingNames[cfg.DurationEncoding]
+		if !ok {
+			return fmt.Errorf("logx: unknown duration encoding %q", cfg.DurationEncoding)
+		}
+		cfg.durationEnc = encoding
+	}
+	if cfg.Time != nil && len(cfg.Time.Encoding) > 0 {
+		encoding, ok := timeEncodingNames[cfg.Time.Encoding]
+		if !ok {
+			return fmt.Errorf("logx: unknown time encoding %q", cfg.Time.Encoding)
+		}
+		cfg.Time.encoding = encoding
+	}
+	if cfg.Sampling != nil && len(cfg.Sampling.Tick) > 0 {
+		if cfg.Sampling.tick, err = time.ParseDuration(cfg.Sampling.Tick); err != nil {
+			return fmt.Errorf("logx: invalid sampling tick: %w", err)
+		}
+	}
+	return nil
+}
+
+// ApplyConfig applies the settings present in cfg to lc. The files listed in
+// the outputs stay open for the life of the process.
+func (lc *LogContext) ApplyConfig(cfg *Config) error {
+	var writer WriteSyncer
+	if len(cfg.Outputs) > 0 {
+		var err error
+		if writer, err = (*outputSet)(nil).open(cfg.Outputs); err != nil {
+			return err
+		}
+	}
+	lc.applyConfig(cfg, writer, configSampler(cfg.Sampling, lc.sampler))
+	return nil
+}
+
+// configSampler returns the sampler of the sampling config, prev is kept if the
+// sampling options are not changed, so that the counters are not reset.
+func configSampler(c *SamplingConfig, prev *sampler) *sampler {
+	if c == nil || !c.Enable {
+		return nil
+	}
+	option := SamplingOption{Tick: c.tick, First: c.First, Thereafter: c.Thereafter}
+	if prev != nil && prev.option == option.withDefaults() {
+		return prev
+	}
+	return newSampler(option)
+}
+
+// applyConfig applies cfg to lc, the writer has been opened from cfg.Outputs and
+// the sampler is created by configSampler.
+func (lc *LogContext) applyConfig(cfg *Config, writer WriteSyncer, sampler *sampler) {
+	if writer != nil {
+		lc.WithWriter(writer)
+	}
+	if len(cfg.Level) > 0 {
+		lc.WithLevel(cfg.level)
+	}
+	if len(cfg.MsgKey) > 0 {
+		lc.WithMsgKey(cfg.MsgKey)
+	}
+	if cfg.Color != nil {
+		lc.WithColorfulset(*cfg.Color, lc.colors.attr)
+	}
+	if cfg.EscapeQuote != nil {
+		lc.WithEscapeQuote(*cfg.EscapeQuote)
+	}
+	if cfg.Development != nil {
+		lc.WithDevelopment(*cfg.Development)
+	}
+	if cfg.ReflectValue != nil {
+		lc.WithReflectValue(*cfg.ReflectValue)
+	}
+	if cfg.SortMapKeys != nil {
+		lc.WithSortedMapKeys(*cfg.SortMapKeys)
+	}
+	if len(cfg.DurationEncoding) > 0 {
+		lc.WithDurationEncoding(cfg.durationEnc)
+	}
+	if c := cfg.LevelKey; c != nil {
+		lc.WithLevelKey(c.Enable, LevelOption{LevelKey: c.Key, LowerKey: c.Lower})
+	}
+	if c := cfg.Time; c != nil {
+		lc.WithTimeKey(c.Enable, TimeOption{
+			TimeKey:   c.Key,
+			Layout:    c.Layout,
+			Timestamp: c.Timestamp,
+			Encoding:  c.encoding,
+			Float:     c.Float,
+			UTC:       c.UTC,
+		})
+	}
+	if c := cfg.Caller; c != nil {
+		lc.WithCallerKey(c.Enable, CallerOption{
+			CallerKey:  c.Key,
+			FileKey:    c.FileKey,
+			FuncKey:    c.FuncKey,
+			Formatter:  c.formatter,
+			CallerSkip: c.Skip,
+		})
+	}
+	if cfg.Sampling != nil {
+		lc.sampler = sampler
+	}
+	if cfg.encoder != 0 {
+		lc.WithEncoder(cfg.encoder)
+	}
+}
+
+// outputSet keeps the outputs opened by a config watcher, so that reloading
+// the same config reuses the files and their locks instead of reopening them.
+type outputSet struct {
+	writers map[string]WriteSyncer
+	files   map[string]*os.File
+}
+
+func (s *outputSet) open(outputs []string) (WriteSyncer, error) {
+	list := make([]WriteSyncer, 0, len(outputs))
+	for _, output := range outputs {
+		if s != nil {
+			if w, ok := s.writers[output]; ok {
+				list = append(list, w)
+				continue
+			}
+		}
+		var w WriteSyncer
+		switch output {
+		case "stdout":
+			w = Lock(AddSync(os.Stdout))
+		case "stderr":
+			w = Lock(AddSync(os.Stderr))
+		default:
+			file, err := os.OpenFile(output, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+			if err != nil {
+				return nil, err
+			}
+			if s != nil {
+				if s.files == nil {
+					s.files = make(map[string]*os.File)
+				}
+				s.files[output] = file
+			}
+			w = Lock(file)
+		}
+		if s != nil {
+			if s.writers == nil {
+				s.writers = make(map[string]WriteSyncer)
+			}
+			s.writers[output] = w
+		}
+		list = append(list, w)
+	}
+	if len(list) == 1 {
+		return list[0], nil
+	}
+	return MultiWriteSyncer(list...), nil
+}
+
+func (s *outputSet) close() {
+	closeFiles(s.release(nil))
+}
+
+// release removes the outputs not in outputs from s and returns their files,
+// which are closed by the caller.
+func (s *outputSet) release(outputs []string) []*os.File {
+	var files []*os.File
+	for output := range s.writers {
+		if slices.Contains(outputs, output) {
+			continue
+		}
+		if file, ok := s.files[output]; ok {
+			files = append(files, file)
+			delete(s.files, output)
+		}
+		delete(s.writers, output)
+	}
+	return files
+}
+
+func closeFiles(files []*os.File) {
+	for _, file := range files {
+		_ = file.Sync()
+		_ = file.Close()
+	}
+}
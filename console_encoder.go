@@ -10,6 +10,7 @@ type ConsoleEncoder struct {
 func (enc *ConsoleEncoder) Init() {
 	enc.jsonEncoder = &JsonEncoder{
 		LogContext: enc.LogContext,
+		console:    true,
 	}
 	enc.jsonEncoder.Init()
 	if enc.callerF.enable {
@@ -19,6 +20,7 @@ func (enc *ConsoleEncoder) Init() {
 }
 
 func (enc *ConsoleEncoder) Encode(ent entry, fields []Field) (ret *Buffer, err error) {
+	enc.jsonEncoder.loadPrefix()
 	jsonEnc := enc.jsonEncoder.clone()
 	defer putJsonEncoder(jsonEnc)
 
@@ -37,35 +39,43 @@ func (enc *ConsoleEncoder) Encode(ent entry, fields []Field) (ret *Buffer, err e
 		buf.AppendByte(ConsoleEncoderSplitCharacter)
 	}
 
-	buf.AppendString(ent.message)
+	msg := ent.message
+	if enc.redactor != nil {
+		msg = enc.redactor.replace(msg)
+	}
+	msg, truncated := truncateString(msg, jsonEnc.msgLimit(0))
+	buf.AppendString(msg)
+	if truncated > 0 {
+		appendTruncatedMarker(buf, truncated, "bytes")
+	}
 
 	n1 := len(fields)
+	if ent.encoded != nil {
+		n1 = ent.encoded.buf.Len()
+	}
 	n2 := len(enc.preFields)
 	if n1 == 0 && n2 == 0 {
+		appendStacktrace(buf, ent.stack)
 		return buf, nil
 	}
 	buf.AppendByte(ConsoleEncoderSplitCharacter)
 
 	jsonEnc.writeBeginObject()
-	jsonEnc.writePrefixFields()
-	if n1 == 0 {
-		jsonEnc.writeEndObject()
-		ret = buf
-		return
-	}
-	if n2 > 0 {
-		jsonEnc.writeSplitComma()
+	if enc.dupKeys.Policy != DuplicateKeepAll {
+		jsonEnc.resolveDuplicateKeys(fields, false)
 	}
-	for i := 0; i < n1; i++ {
-		if err = jsonEnc.writeField(&fields[i]); err != nil {
-			bufPool.Put(buf)
-			return
-		}
-		if i+1 != n1 {
-			jsonEnc.writeSplitComma()
-		}
+	jsonEnc.writePrefixFields(0, len(jsonEnc.prefixEnds))
+	if ent.encoded != nil {
+		jsonEnc.appendEncoded(ent.encoded)
+	} else if err = jsonEnc.writeFields(fields); err != nil {
+		bufPool.Put(buf)
+		return
 	}
+	jsonEnc.closeNamespaces(0)
 	jsonEnc.writeEndObject()
+	appendHexDumps(buf, enc.jsonEncoder.prefixDumps, enc.binary.MaxBytes)
+	appendHexDumps(buf, jsonEnc.hexDumps, enc.binary.MaxBytes)
+	appendStacktrace(buf, ent.stack)
 	ret = buf
 	return
 }
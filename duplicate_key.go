@@ -0,0 +1,322 @@
+package logx
+
+import (
+	"slices"
+	"strconv"
+	"time"
+)
+
+type DuplicateKeyPolicy uint8
+
+const (
+	// write all the fields even if their keys collide
+	DuplicateKeepAll DuplicateKeyPolicy = iota
+	// drop all but the last field with the same key
+	DuplicateLastWins
+	// drop all but the first field with the same key
+	DuplicateFirstWins
+	// rename the following fields with the same key by appending a suffix and a sequence number
+	DuplicateRename
+)
+
+type DuplicateKeyOption struct {
+	// duplicate key policy, default: DuplicateKeepAll
+	Policy DuplicateKeyPolicy
+	// suffix of the renamed keys, followed by a sequence number, default: "_"
+	Suffix string
+}
+
+// keyAction is the resolution of a top-level field key, the field is dropped
+// or written with the renamed key if not empty. The other top-level keys written
+// by the field, i.e. the keys of an Inline field and the detail keys of an Error
+// field, are resolved one by one into subKeys[from:to] of the encoder.
+type keyAction struct {
+	drop     bool
+	key      string
+	from, to int
+	// some of the subKeys are dropped or renamed
+	changed bool
+}
+
+// subKeyAction is the resolution of a top-level key written by a field besides
+// its own key.
+type subKeyAction struct {
+	// the key written by the field
+	name string
+	drop bool
+	key  string
+}
+
+// fieldKey is a top-level key written by a field, in the order of writing.
+type fieldKey struct {
+	field int
+	name  string
+	// the own key of the field instead of a key written by it
+	own       bool
+	namespace bool
+	// the detail key of the error key at base, which is named after the
+	// resolved key at base followed by the suffix, otherwise base is -1
+	base   int
+	suffix string
+	// the resolution
+	drop bool
+	key  string
+}
+
+// resolveDuplicateKeys resolves the key collisions between the reserved keys,
+// the preFields and the fields into enc.keyActions, which has an action for each
+// of the preFields followed by the fields. The reserved level, time, caller and
+// msg keys always win over the other fields. A namespace is never dropped or
+// renamed, the fields following it are resolved in a new scope of keys.
+func (enc *JsonEncoder) resolveDuplicateKeys(fields []Field, reserved bool) {
+	pre := enc.preFields
+	n := len(pre) + len(fields)
+	enc.keyActions = slices.Grow(enc.keyActions[:0], n)[:n]
+	clear(enc.keyActions)
+	enc.seenKeys = enc.seenKeys[:0]
+	enc.subKeys = enc.subKeys[:0]
+
+	if reserved {
+		if enc.levelF.enable {
+			enc.seenKeys = append(enc.seenKeys, enc.levelF.option.LevelKey)
+		}
+		if enc.timeF.enable {
+			enc.seenKeys = append(enc.seenKeys, enc.timeF.option.TimeKey)
+		}
+		if enc.callerF.enable {
+			enc.seenKeys = append(enc.seenKeys, enc.callerF.option.CallerKey)
+		}
+		enc.seenKeys = append(enc.seenKeys, enc.msgKey)
+	}
+	nReserved := len(enc.seenKeys)
+
+	enc.fieldKeys = enc.fieldKeys[:0]
+	for i := range pre {
+		enc.collectKeys(i, &pre[i], true)
+	}
+	for i := range fields {
+		enc.collectKeys(len(pre)+i, &fields[i], true)
+	}
+	keys := enc.fieldKeys
+
+	for k := range keys {
+		fk := &keys[k]
+		if fk.namespace {
+			enc.seenKeys = enc.seenKeys[:0]
+			nReserved = 0
+			continue
+		}
+		if fk.base >= 0 {
+			// the details of the dropped errors are not written
+			if base := &keys[fk.base]; base.drop {
+				fk.drop = true
+				continue
+			} else if len(base.key) > 0 {
+				fk.name = base.key + fk.suffix
+			}
+		}
+		key := fk.name
+		switch enc.dupKeys.Policy {
+		case DuplicateFirstWins:
+			if slices.Contains(enc.seenKeys, key) {
+				fk.drop = true
+				continue
+			}
+		case DuplicateLastWins:
+			if slices.Contains(enc.seenKeys[:nReserved], key) {
+				fk.drop = true
+				continue
+			}
+			for _, g := range keys[k+1:] {
+				if g.namespace {
+					// the following keys are in the scope of the namespace
+					fk.drop = g.name == key
+					break
+				}
+				if g.name == key {
+					fk.drop = true
+					break
+				}
+			}
+			continue
+		case DuplicateRename:
+			if slices.Contains(enc.seenKeys, key) {
+				for seq := 1; ; seq++ {
+					renamed := key + enc.dupKeys.Suffix + strconv.Itoa(seq)
+					if !slices.Contains(enc.seenKeys, renamed) {
+						key = renamed
+						break
+					}
+				}
+				fk.key = key
+			}
+		}
+		enc.seenKeys = append(enc.seenKeys, key)
+	}
+
+	for k := range keys {
+		fk := &keys[k]
+		action := &enc.keyActions[fk.field]
+		if fk.own {
+			action.drop, action.key = fk.drop, fk.key
+			continue
+		}
+		// the namespaces are never dropped or renamed
+		if fk.namespace {
+			continue
+		}
+		// the keys of a field are contiguous
+		if action.from == action.to {
+			action.from = len(enc.subKeys)
+		}
+		enc.subKeys = append(enc.subKeys, subKeyAction{name: fk.name, drop: fk.drop, key: fk.key})
+		action.to = len(enc.subKeys)
+		action.changed = action.changed || fk.drop || len(fk.key) > 0
+	}
+}
+
+// collectKeys appends the top-level keys written by the field at index i to
+// enc.fieldKeys, the keys of the Inline fields are recorded by marshaling them.
+func (enc *JsonEncoder) collectKeys(i int, field *Field, own bool) {
+	switch field.Type {
+	case NoneType:
+	case NamespaceType:
+		enc.fieldKeys = append(enc.fieldKeys, fieldKey{field: i, name: field.Key, own: own, namespace: true, base: -1})
+	case InlineType:
+		enc.recorder.enc, enc.recorder.field = enc, i
+		if err := field.AnyValue.(ObjectMarshaler).MarshalLogObject(&enc.recorder); err != nil {
+			enc.recorder.AddString("error", "")
+		}
+	case ErrorType:
+		enc.fieldKeys = append(enc.fieldKeys, fieldKey{field: i, name: field.Key, own: own, base: -1})
+		if field.AnyValue != nil {
+			enc.collectErrorKeys(i, field.AnyValue.(error))
+		}
+	default:
+		enc.fieldKeys = append(enc.fieldKeys, fieldKey{field: i, name: field.Key, own: own, base: -1})
+	}
+}
+
+// collectErrorKeys appends the detail keys of the error, whose key is the last
+// one of enc.fieldKeys.
+func (enc *JsonEncoder) collectErrorKeys(i int, err error) {
+	if enc.errorOpt == (ErrorOption{}) {
+		return
+	}
+	base := len(enc.fieldKeys) - 1
+	suffixes, n := enc.errorOpt.detailSuffixes(err)
+	for _, suffix := range suffixes[:n] {
+		enc.fieldKeys = append(enc.fieldKeys, fieldKey{
+			field:  i,
+			name:   enc.fieldKeys[base].name + suffix,
+			base:   base,
+			suffix: suffix,
+		})
+	}
+}
+
+// keyRecorder is the ObjectEncoder recording the top-level keys written by the
+// Inline fields, the values are not encoded.
+type keyRecorder struct {
+	enc   *JsonEncoder
+	field int
+}
+
+func (r *keyRecorder) add(key string) {
+	r.enc.fieldKeys = append(r.enc.fieldKeys, fieldKey{field: r.field, name: key, base: -1})
+}
+
+func (r *keyRecorder) AddString(key, value string)                 { r.add(key) }
+func (r *keyRecorder) AddBool(key string, value bool)              { r.add(key) }
+func (r *keyRecorder) AddInt(key string, value int)                { r.add(key) }
+func (r *keyRecorder) AddInt64(key string, value int64)            { r.add(key) }
+func (r *keyRecorder) AddUint64(key string, value uint64)          { r.add(key) }
+func (r *keyRecorder) AddFloat64(key string, value float64)        { r.add(key) }
+func (r *keyRecorder) AddTime(key string, value time.Time)         { r.add(key) }
+func (r *keyRecorder) AddDuration(key string, value time.Duration) { r.add(key) }
+func (r *keyRecorder) AddAny(key string, value any)                { r.add(key) }
+
+func (r *keyRecorder) AddError(key string, value error) {
+	r.add(key)
+	if value != nil {
+		r.enc.collectErrorKeys(r.field, value)
+	}
+}
+
+func (r *keyRecorder) AddField(field Field) {
+	// the nested Inline fields overwrite the field index with the same one
+	r.enc.collectKeys(r.field, &field, false)
+}
+
+func (r *keyRecorder) AddObject(key string, value ObjectMarshaler) error {
+	r.add(key)
+	return nil
+}
+
+func (r *keyRecorder) AddArray(key string, value ArrayMarshaler) error {
+	r.add(key)
+	return nil
+}
+
+func (r *keyRecorder) OpenNamespace(key string) {
+	r.enc.fieldKeys = append(r.enc.fieldKeys, fieldKey{field: r.field, name: key, namespace: true, base: -1})
+}
+
+// filterKey resolves a top-level key written by the field being written with
+// the subKeys in enc.keyFilter, which are matched in the order of writing.
+func (enc *JsonEncoder) filterKey(key string) (string, bool) {
+	if len(enc.keyFilter) == 0 || enc.depth-enc.openNamespaces != enc.keyFilterLevel {
+		return key, true
+	}
+	for i := range enc.keyFilter {
+		sub := &enc.keyFilter[i]
+		if sub.name != key {
+			continue
+		}
+		enc.keyFilter = enc.keyFilter[i+1:]
+		if sub.drop {
+			return "", false
+		}
+		if len(sub.key) > 0 {
+			return sub.key, true
+		}
+		break
+	}
+	return key, true
+}
+
+// writeResolvedField writes the field according to its key action.
+func (enc *JsonEncoder) writeResolvedField(field *Field, action *keyAction) (err error) {
+	if action.drop {
+		return nil
+	}
+	if action.changed {
+		enc.keyFilter = enc.subKeys[action.from:action.to]
+		enc.keyFilterLevel = enc.depth - enc.openNamespaces
+	}
+	if len(action.key) > 0 {
+		renamed := *field
+		renamed.Key = action.key
+		err = enc.writeField(&renamed)
+	} else {
+		err = enc.writeField(field)
+	}
+	enc.keyFilter = nil
+	return
+}
+
+// writeResolvedPrefixField writes the cached preField at index i according to
+// its key action, only the changed fields are encoded again.
+func (enc *JsonEncoder) writeResolvedPrefixField(i int) {
+	action := &enc.keyActions[i]
+	switch {
+	case action.drop:
+	case len(action.key) > 0 || action.changed:
+		// the namespaces of the preFields are counted already
+		open, depth := enc.openNamespaces, enc.depth
+		enc.writeResolvedField(&enc.preFields[i], action)
+		enc.openNamespaces, enc.depth = open, depth
+	default:
+		enc.writePrefixSpan(i, i+1)
+	}
+}
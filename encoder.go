@@ -13,6 +13,10 @@ type entry struct {
 	level   LevelType
 	time    time.Time
 	message string
+	// the fields encoded by an Event, which are written instead of the fields
+	encoded *JsonEncoder
+	// the stacktrace of the log call if enabled
+	stack *stacktrace
 }
 
 type encoder interface {
@@ -0,0 +1,116 @@
+package logx
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorOption enables the details of the Error fields, which are written as the
+// sibling keys of the field with the suffixes below, e.g. "errorVerbose".
+type ErrorOption struct {
+	// write "%+v" of the errors implementing fmt.Formatter under <key>Verbose,
+	// e.g. the errors carrying stack traces, default: false
+	Verbose bool
+	// write the messages of the errors.Unwrap chain under <key>Causes, default: false
+	Causes bool
+	// write the messages of the errors.Join members under <key>Errors, default: false
+	Joined bool
+	// write the key-values of the errors implementing ObjectMarshaler in the
+	// errors.Unwrap chain under <key>Fields, default: false
+	Fields bool
+}
+
+type joinedError interface {
+	Unwrap() []error
+}
+
+// writeErrorDetails writes the details of a non-nil error field after the
+// field itself, the details are resolved by detailSuffixes in the same order.
+func (enc *JsonEncoder) writeErrorDetails(key string, err error) {
+	option := &enc.errorOpt
+	if option.Verbose {
+		if _, ok := err.(fmt.Formatter); ok {
+			enc.AddString(key+"Verbose", fmt.Sprintf("%+v", err))
+		}
+	}
+	if option.Causes {
+		if cause := errors.Unwrap(err); cause != nil {
+			if key, ok := enc.filterKey(key + "Causes"); ok {
+				enc.writeKey(key)
+				enc.writeBeginArray()
+				for ; cause != nil; cause = errors.Unwrap(cause) {
+					enc.AppendString(cause.Error())
+				}
+				enc.writeEndArray()
+			}
+		}
+	}
+	if option.Joined {
+		if joined := joinedCause(err); joined != nil {
+			if key, ok := enc.filterKey(key + "Errors"); ok {
+				enc.writeKey(key)
+				enc.writeBeginArray()
+				for _, member := range joined.Unwrap() {
+					enc.AppendError(member)
+				}
+				enc.writeEndArray()
+			}
+		}
+	}
+	if option.Fields && hasObjectCause(err) {
+		key, ok := enc.filterKey(key + "Fields")
+		if !ok {
+			return
+		}
+		enc.writeKey(key)
+		enc.writeBeginObject()
+		for e := err; e != nil; e = errors.Unwrap(e) {
+			if marshaler, ok := e.(ObjectMarshaler); ok {
+				n := enc.openNamespaces
+				marshaler.MarshalLogObject(enc)
+				enc.closeNamespaces(n)
+			}
+		}
+		enc.writeEndObject()
+	}
+}
+
+// detailSuffixes returns the suffixes of the detail keys written for the err.
+func (option *ErrorOption) detailSuffixes(err error) (suffixes [4]string, n int) {
+	if _, ok := err.(fmt.Formatter); ok && option.Verbose {
+		suffixes[n] = "Verbose"
+		n++
+	}
+	if option.Causes && errors.Unwrap(err) != nil {
+		suffixes[n] = "Causes"
+		n++
+	}
+	if option.Joined && joinedCause(err) != nil {
+		suffixes[n] = "Errors"
+		n++
+	}
+	if option.Fields && hasObjectCause(err) {
+		suffixes[n] = "Fields"
+		n++
+	}
+	return
+}
+
+// joinedCause returns the first errors.Join error in the errors.Unwrap chain.
+func joinedCause(err error) joinedError {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if joined, ok := e.(joinedError); ok {
+			return joined
+		}
+	}
+	return nil
+}
+
+func hasObjectCause(err error) bool {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if _, ok := e.(ObjectMarshaler); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,271 @@
+package logx
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+var eventPool = sync.Pool{New: func() any { return new(Event) }}
+
+// EventLogger builds the entries of the logger with the fluent events, e.g.
+//
+//	logger.Events().Info().Str("key", "value").Int("n", 1).Err(err).Msg("done")
+//
+// The fields are encoded into a pooled buffer as they are added instead of being
+// collected as Fields. The events share the configuration, the caller skipping
+// and the context fields of the logger. If the duplicate key policy or the max
+// entry size is set, the fields are collected and written like the fields of the
+// log calls instead, so that they're resolved and limited with the others.
+type EventLogger struct {
+	logger *LoggerX
+}
+
+func (l *LoggerX) Events() EventLogger { return EventLogger{logger: l} }
+
+func (l EventLogger) Trace() *Event { return l.newEvent(LevelTrace) }
+
+func (l EventLogger) Debug() *Event { return l.newEvent(LevelDebug) }
+
+func (l EventLogger) Info() *Event { return l.newEvent(LevelInfo) }
+
+func (l EventLogger) Warn() *Event { return l.newEvent(LevelWarn) }
+
+func (l EventLogger) Error() *Event { return l.newEvent(LevelError) }
+
+// DPanic returns an event which panics after the entry is written in the
+// development mode, it panics even if the entry is discarded.
+func (l EventLogger) DPanic() *Event { return l.newEvent(LevelDPanic) }
+
+// Panic returns an event which panics after the entry is written, it panics
+// even if the entry is discarded.
+func (l EventLogger) Panic() *Event { return l.newEvent(LevelPanic) }
+
+// Fatal returns an event which calls os.Exit(1) after the entry is written, it
+// exits even if the entry is discarded.
+func (l EventLogger) Fatal() *Event { return l.newEvent(LevelFatal) }
+
+func (l EventLogger) With(fields ...Field) EventLogger {
+	return EventLogger{logger: l.logger.clone(false, fields...)}
+}
+
+func (l EventLogger) newEvent(level LevelType) *Event {
+	lc := l.logger.context()
+	// discard the log
+	enabled := lc.writer != nil && lc.writer != io.Discard && lc.levelT <= level
+	if !enabled && level < LevelFatal && (level != LevelDPanic || !lc.development) {
+		return nil
+	}
+	e := eventPool.Get().(*Event)
+	e.logger, e.lc, e.level, e.enabled = l.logger, lc, level, enabled
+	jsonEnc := lc.jsonEncoder()
+	if !enabled || jsonEnc == nil || lc.dupKeys.Policy != DuplicateKeepAll || lc.limits.MaxEntrySize > 0 {
+		return e
+	}
+	jsonEnc.loadPrefix()
+	e.enc = jsonEnc.clone()
+	// the fields follow the namespaces of the preFields
+	e.enc.depth = 1 + jsonEnc.prefixNamespaces
+	return e
+}
+
+func putEvent(e *Event) {
+	if e.enc != nil {
+		bufPool.Put(e.enc.buf)
+		putJsonEncoder(e.enc)
+	}
+	clear(e.fields)
+	e.logger, e.lc, e.enc, e.fields = nil, nil, nil, e.fields[:0]
+	eventPool.Put(e)
+}
+
+// Event is an entry being built, it's finished by Msg, Msgf or Send and must
+// not be used after that. All methods of a nil Event, which is returned if the
+// level is disabled, are no-ops.
+type Event struct {
+	logger *LoggerX
+	lc     *LogContext
+	// the buffer of the encoded fields, or nil if the fields are collected
+	enc    *JsonEncoder
+	fields []Field
+	level  LevelType
+	// the entry is written, otherwise the fields are dropped
+	enabled bool
+}
+
+// add writes the field into the buffer of the event, or collects it.
+func (e *Event) add(field Field) {
+	switch {
+	case e.enc != nil:
+		_ = e.enc.writeField(&field)
+	case e.enabled:
+		e.fields = append(e.fields, field)
+	}
+}
+
+func (e *Event) Str(key, value string) *Event {
+	if e != nil {
+		e.add(String(key, value))
+	}
+	return e
+}
+
+func (e *Event) Bool(key string, value bool) *Event {
+	if e != nil {
+		e.add(Bool(key, value))
+	}
+	return e
+}
+
+func (e *Event) Int(key string, value int) *Event {
+	if e != nil {
+		e.add(Int(key, value))
+	}
+	return e
+}
+
+func (e *Event) Int64(key string, value int64) *Event {
+	if e != nil {
+		e.add(Int64(key, value))
+	}
+	return e
+}
+
+func (e *Event) Uint64(key string, value uint64) *Event {
+	if e != nil {
+		e.add(UInt64(key, value))
+	}
+	return e
+}
+
+func (e *Event) Float64(key string, value float64) *Event {
+	if e != nil {
+		e.add(Float64(key, value))
+	}
+	return e
+}
+
+func (e *Event) Time(key string, value time.Time) *Event {
+	if e != nil {
+		e.add(Time(key, value))
+	}
+	return e
+}
+
+func (e *Event) Dur(key string, value time.Duration) *Event {
+	if e != nil {
+		e.add(Duration(key, value))
+	}
+	return e
+}
+
+// Err adds the err under the key "error", the nil err is skipped.
+func (e *Event) Err(err error) *Event {
+	return e.AnErr("error", err)
+}
+
+// AnErr adds the err under the key, the nil err is skipped.
+func (e *Event) AnErr(key string, err error) *Event {
+	if e != nil && err != nil {
+		e.add(Error(key, err))
+	}
+	return e
+}
+
+func (e *Event) Any(key string, value any) *Event {
+	if e != nil {
+		e.add(Any(key, value))
+	}
+	return e
+}
+
+func (e *Event) Object(key string, value ObjectMarshaler) *Event {
+	if e != nil {
+		e.add(MarshalObject(key, value))
+	}
+	return e
+}
+
+func (e *Event) Array(key string, value ArrayMarshaler) *Event {
+	if e != nil {
+		e.add(MarshalArray(key, value))
+	}
+	return e
+}
+
+// Namespace nests the following fields of the event under the key.
+func (e *Event) Namespace(key string) *Event {
+	if e != nil {
+		e.add(Namespace(key))
+	}
+	return e
+}
+
+func (e *Event) Fields(fields ...Field) *Event {
+	if e != nil {
+		for i := range fields {
+			e.add(fields[i])
+		}
+	}
+	return e
+}
+
+// Msg writes the entry with the msg.
+func (e *Event) Msg(msg string) {
+	if e == nil {
+		return
+	}
+	e.logger.printEvent(e, msg)
+}
+
+// Msgf writes the entry with the formatted msg.
+func (e *Event) Msgf(format string, args ...any) {
+	if e == nil {
+		return
+	}
+	e.logger.printEvent(e, fmt.Sprintf(format, args...))
+}
+
+// Send writes the entry with an empty msg.
+func (e *Event) Send() {
+	if e == nil {
+		return
+	}
+	e.logger.printEvent(e, "")
+}
+
+// printEvent is the print of the events, it's called at the same call depth
+// as print so that the caller is reported correctly.
+func (l *LoggerX) printEvent(e *Event, msg string) {
+	lc, level := e.lc, e.level
+	if e.enabled {
+		now := time.Now()
+		if lc.sampler == nil || lc.sampler.check(level, msg, now) {
+			l.output(lc, entry{level: level, message: msg, time: now, encoded: e.enc}, e.fields)
+		}
+	}
+	putEvent(e)
+	switch level {
+	case LevelDPanic:
+		if lc.development {
+			panic(msg)
+		}
+	case LevelPanic:
+		panic(msg)
+	case LevelFatal:
+		os.Exit(1)
+	}
+}
+
+// appendEncoded appends the fields encoded by an event.
+func (enc *JsonEncoder) appendEncoded(fields *JsonEncoder) {
+	if fields.buf.Len() == 0 {
+		return
+	}
+	enc.addElementSeparator()
+	enc.buf.AppendBytes(fields.buf.Bytes())
+	enc.openNamespaces += fields.openNamespaces
+	enc.hexDumps = append(enc.hexDumps, fields.hexDumps...)
+}
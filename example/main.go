@@ -175,8 +175,8 @@ func goroutinesPrintLogs() {
 		WithWriter(logx.AddSync(file)).
 		WithEncoder(logx.Console)
 
-	logger := lc.Copy().Build()
-	logger2 := lc.Copy().Build()
+	logger := lc.Build()
+	logger2 := lc.Build()
 	logger.Info("hello")
 	logger2.Info("hello")
 
@@ -1,9 +1,18 @@
 package logx
 
 import (
+	"cmp"
 	"errors"
+	"fmt"
+	"maps"
 	"math"
+	"net"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
 	"time"
+	"unsafe"
 )
 
 const (
@@ -30,6 +39,37 @@ const (
 	ArrayType
 	NilType
 	AnyType
+	ObjectMarshalerType
+	ArrayMarshalerType
+	InlineType
+	NamespaceType
+	LazyType
+	LazyObjectType
+	BinaryType
+	ByteStringType
+	StringsType
+	BoolsType
+	IntsType
+	Int8sType
+	Int16sType
+	Int32sType
+	Int64sType
+	UintsType
+	Uint8sType
+	Uint16sType
+	Uint32sType
+	Uint64sType
+	Float32sType
+	Float64sType
+	TimesType
+	DurationsType
+	IPType
+	AddrType
+	AddrsType
+	AddrPortType
+	PrefixType
+	HardwareAddrType
+	URLType
 )
 
 var (
@@ -122,6 +162,46 @@ func Duration(key string, value time.Duration) Field {
 	return Field{Key: key, Type: DurationType, IntValue: int64(value)}
 }
 
+// pointerField encodes null for the nil pointers, otherwise the value with f.
+func pointerField[T any](key string, value *T, f func(string, T) Field) Field {
+	if value == nil {
+		return Field{Key: key, Type: NilType}
+	}
+	return f(key, *value)
+}
+
+func Stringp(key string, value *string) Field { return pointerField(key, value, String) }
+
+func Boolp(key string, value *bool) Field { return pointerField(key, value, Bool) }
+
+func Int8p(key string, value *int8) Field { return pointerField(key, value, Int8) }
+
+func Int16p(key string, value *int16) Field { return pointerField(key, value, Int16) }
+
+func Int32p(key string, value *int32) Field { return pointerField(key, value, Int32) }
+
+func Int64p(key string, value *int64) Field { return pointerField(key, value, Int64) }
+
+func Intp(key string, value *int) Field { return pointerField(key, value, Int) }
+
+func UInt8p(key string, value *uint8) Field { return pointerField(key, value, UInt8) }
+
+func UInt16p(key string, value *uint16) Field { return pointerField(key, value, UInt16) }
+
+func UInt32p(key string, value *uint32) Field { return pointerField(key, value, UInt32) }
+
+func UInt64p(key string, value *uint64) Field { return pointerField(key, value, UInt64) }
+
+func UIntp(key string, value *uint) Field { return pointerField(key, value, UInt) }
+
+func Float32p(key string, value *float32) Field { return pointerField(key, value, Float32) }
+
+func Float64p(key string, value *float64) Field { return pointerField(key, value, Float64) }
+
+func Timep(key string, value *time.Time) Field { return pointerField(key, value, Time) }
+
+func Durationp(key string, value *time.Duration) Field { return pointerField(key, value, Duration) }
+
 func Error(key string, value error) Field {
 	return Field{Key: key, Type: ErrorType, AnyValue: value}
 }
@@ -138,6 +218,168 @@ func ArrayT[T any](key string, value ...T) Field {
 	return Field{Key: key, Type: ArrayType, AnyValue: value}
 }
 
+// MarshalObject encodes value as an object with its MarshalLogObject method.
+func MarshalObject(key string, value ObjectMarshaler) Field {
+	return Field{Key: key, Type: ObjectMarshalerType, AnyValue: value}
+}
+
+// MarshalArray encodes value as an array with its MarshalLogArray method.
+func MarshalArray(key string, value ArrayMarshaler) Field {
+	return Field{Key: key, Type: ArrayMarshalerType, AnyValue: value}
+}
+
+// Inline merges the key-values of value into the parent object, instead of
+// nesting them under a key.
+func Inline(value ObjectMarshaler) Field {
+	return Field{Type: InlineType, AnyValue: value}
+}
+
+// Namespace opens a nested object with the key, all the following fields of the
+// log entry are written into it until the end of the entry.
+func Namespace(key string) Field {
+	return Field{Key: key, Type: NamespaceType}
+}
+
+// Lazy calls value to get the field value only when the entry is written, i.e.
+// after the level and sampling checks. In the context fields it's called once
+// per logger when the fields are encoded, which is deferred until first use by
+// WithLazy.
+func Lazy(key string, value func() any) Field {
+	return Field{Key: key, Type: LazyType, AnyValue: value}
+}
+
+// LazyObject is like Lazy but calls value to get the fields of an object.
+func LazyObject(key string, value func() []Field) Field {
+	return Field{Key: key, Type: LazyObjectType, AnyValue: value}
+}
+
+// Binary encodes the binary value with the BinaryOption of the LogContext, the
+// value is not copied and must not be modified until the entry is written. The
+// nil value is encoded as null.
+func Binary(key string, value []byte) Field {
+	if value == nil {
+		return Field{Key: key, Type: NilType}
+	}
+	return Field{Key: key, Type: BinaryType, StringValue: unsafe.String(unsafe.SliceData(value), len(value))}
+}
+
+// ByteString encodes the UTF-8 encoded text as a string without copying it.
+func ByteString(key string, value []byte) Field {
+	return Field{Key: key, Type: ByteStringType, StringValue: unsafe.String(unsafe.SliceData(value), len(value))}
+}
+
+// Map encodes a map with ordered keys as an object, the keys are always
+// written in sorted order without using reflection.
+func Map[K cmp.Ordered, V any](key string, value map[K]V) Field {
+	return Field{Key: key, Type: ObjectMarshalerType, AnyValue: orderedMap[K, V](value)}
+}
+
+type orderedMap[K cmp.Ordered, V any] map[K]V
+
+func (m orderedMap[K, V]) MarshalLogObject(enc ObjectEncoder) error {
+	for _, k := range slices.Sorted(maps.Keys(m)) {
+		enc.AddAny(orderedKeyString(k), m[k])
+	}
+	return nil
+}
+
+func orderedKeyString[K cmp.Ordered](k K) string {
+	switch k := any(k).(type) {
+	case string:
+		return k
+	case int:
+		return strconv.FormatInt(int64(k), 10)
+	case int8:
+		return strconv.FormatInt(int64(k), 10)
+	case int16:
+		return strconv.FormatInt(int64(k), 10)
+	case int32:
+		return strconv.FormatInt(int64(k), 10)
+	case int64:
+		return strconv.FormatInt(k, 10)
+	case uint:
+		return strconv.FormatUint(uint64(k), 10)
+	case uint8:
+		return strconv.FormatUint(uint64(k), 10)
+	case uint16:
+		return strconv.FormatUint(uint64(k), 10)
+	case uint32:
+		return strconv.FormatUint(uint64(k), 10)
+	case uint64:
+		return strconv.FormatUint(k, 10)
+	case uintptr:
+		return strconv.FormatUint(uint64(k), 10)
+	case float32:
+		return strconv.FormatFloat(float64(k), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(k, 'g', -1, 64)
+	default:
+		// the named types, e.g. time.Duration
+		return fmt.Sprint(k)
+	}
+}
+
+// Any returns the field of the type of value, or the AnyType field which is
+// encoded with reflection if enabled. Like Binary, IP and HardwareAddr, the
+// []byte, net.IP and net.HardwareAddr values are not copied and must not be
+// modified until the entry is written, the fields of the loggers are copied
+// once they're encoded.
 func Any(key string, value any) Field {
+	switch v := value.(type) {
+	case ObjectMarshaler:
+		return MarshalObject(key, v)
+	case ArrayMarshaler:
+		return MarshalArray(key, v)
+	case error:
+		return Error(key, v)
+	case []byte:
+		return Binary(key, v)
+	case net.IP:
+		return IP(key, v)
+	case net.HardwareAddr:
+		return HardwareAddr(key, v)
+	case netip.Addr:
+		return Addr(key, v)
+	case []netip.Addr:
+		return Addrs(key, v)
+	case netip.AddrPort:
+		return AddrPort(key, v)
+	case netip.Prefix:
+		return Prefix(key, v)
+	case *url.URL:
+		return URL(key, v)
+	case *string:
+		return Stringp(key, v)
+	case *bool:
+		return Boolp(key, v)
+	case *int8:
+		return Int8p(key, v)
+	case *int16:
+		return Int16p(key, v)
+	case *int32:
+		return Int32p(key, v)
+	case *int64:
+		return Int64p(key, v)
+	case *int:
+		return Intp(key, v)
+	case *uint8:
+		return UInt8p(key, v)
+	case *uint16:
+		return UInt16p(key, v)
+	case *uint32:
+		return UInt32p(key, v)
+	case *uint64:
+		return UInt64p(key, v)
+	case *uint:
+		return UIntp(key, v)
+	case *float32:
+		return Float32p(key, v)
+	case *float64:
+		return Float64p(key, v)
+	case *time.Time:
+		return Timep(key, v)
+	case *time.Duration:
+		return Durationp(key, v)
+	}
 	return Field{Key: key, Type: AnyType, AnyValue: value}
 }
@@ -2,10 +2,16 @@ package logx
 
 import (
 	"fmt"
+	"maps"
 	"math"
+	"net"
+	"net/netip"
+	"net/url"
 	"reflect"
+	"slices"
 	"sync"
 	"time"
+	"unsafe"
 )
 
 var jsonPool = sync.Pool{New: func() any { return &JsonEncoder{} }}
@@ -13,6 +19,41 @@ var jsonPool = sync.Pool{New: func() any { return &JsonEncoder{} }}
 type JsonEncoder struct {
 	*LogContext
 	buf *Buffer
+	// the encoded preFields, which are encoded once instead of on every log call
+	prefix []byte
+	// the end offsets of each of the encoded preFields in prefix
+	prefixEnds []int
+	// the number of namespaces opened by each of the preFields and the ones before
+	prefixOpens []int
+	// the index of the first namespace in preFields, the msg is written before it
+	prefixNamespace int
+	// the number of namespaces opened by preFields
+	prefixNamespaces int
+	// encode the preFields on first use if not nil
+	prefixOnce *sync.Once
+	// the number of namespaces opened and not closed yet
+	openNamespaces int
+	// the nesting depth of the objects and arrays
+	depth int
+	// the bytes of the fields dropped to fit the max entry size
+	truncated int
+	// the maps, slices and pointers being encoded to detect the cycles
+	visited []visitedRef
+	// used by the console encoder to dump the top-level Binary fields
+	console bool
+	// write the values without the colors and the escapes to render templates
+	plain       bool
+	hexDumps    []hexDump
+	prefixDumps []hexDump
+	// the scratch space to resolve the duplicate keys
+	keyActions []keyAction
+	seenKeys   []string
+	fieldKeys  []fieldKey
+	subKeys    []subKeyAction
+	recorder   keyRecorder
+	// the subKeys of the field being written and the depth of its keys
+	keyFilter      []subKeyAction
+	keyFilterLevel int
 }
 
 func (enc *JsonEncoder) Init() {
@@ -23,11 +64,61 @@ func (enc *JsonEncoder) Init() {
 	enc.colors.init()
 	enc.timeF.numberColor = enc.colors.attr.NumberColor
 	enc.timeF.stringColor = enc.colors.attr.StringColor
+	enc.timeF.init()
+	if enc.lazyPrefix {
+		enc.prefix, enc.prefixEnds, enc.prefixOpens = nil, nil, nil
+		enc.prefixOnce = new(sync.Once)
+	} else {
+		enc.prefixOnce = nil
+		enc.encodePrefixFields()
+	}
+}
+
+// loadPrefix encodes the preFields of the lazy encoders on first use.
+func (enc *JsonEncoder) loadPrefix() {
+	if enc.prefixOnce != nil {
+		enc.prefixOnce.Do(enc.encodePrefixFields)
+	}
+}
+
+// encodePrefixFields caches the encoded preFields with the current color and
+// escape settings, it must be called again once the preFields changed.
+func (enc *JsonEncoder) encodePrefixFields() {
+	enc.prefix, enc.prefixEnds, enc.prefixOpens, enc.prefixDumps = nil, nil, nil, nil
+	n := len(enc.preFields)
+	enc.prefixNamespace, enc.prefixNamespaces = n, 0
+	if n == 0 {
+		return
+	}
+	nenc := enc.clone()
+	defer putJsonEncoder(nenc)
+	// the preFields are written into the entry object
+	nenc.depth = 1
+	ends, opens := make([]int, n), make([]int, n)
+	for i := 0; i < n; i++ {
+		nenc.writeField(&enc.preFields[i])
+		ends[i], opens[i] = nenc.buf.Len(), nenc.openNamespaces
+		if nenc.openNamespaces > 0 && enc.prefixNamespaces == 0 {
+			enc.prefixNamespace = i
+		}
+		enc.prefixNamespaces = nenc.openNamespaces
+	}
+	// the namespaces are left open and closed at the end of each entry
+	enc.prefix, enc.prefixEnds, enc.prefixOpens = slices.Clone(nenc.buf.Bytes()), ends, opens
+	// the dumps are written after every entry, don't keep the caller's slices
+	enc.prefixDumps = slices.Clone(nenc.hexDumps)
+	for i := range enc.prefixDumps {
+		enc.prefixDumps[i].value = slices.Clone(enc.prefixDumps[i].value)
+	}
+	bufPool.Put(nenc.buf)
 }
 
 func (enc *JsonEncoder) clone() *JsonEncoder {
 	clone := jsonPool.Get().(*JsonEncoder)
 	clone.LogContext = enc.LogContext
+	clone.prefix, clone.prefixEnds, clone.prefixOpens = enc.prefix, enc.prefixEnds, enc.prefixOpens
+	clone.prefixNamespace, clone.prefixNamespaces = enc.prefixNamespace, enc.prefixNamespaces
+	clone.console = enc.console
 	clone.buf = bufPool.Get().(*Buffer)
 	clone.buf.Reset()
 	return clone
@@ -36,83 +127,188 @@ func (enc *JsonEncoder) clone() *JsonEncoder {
 func putJsonEncoder(enc *JsonEncoder) {
 	enc.LogContext = nil
 	enc.buf = nil
+	enc.prefix, enc.prefixEnds, enc.prefixOpens = nil, nil, nil
+	enc.openNamespaces = 0
+	enc.depth = 0
+	enc.truncated = 0
+	enc.plain = false
+	clear(enc.visited)
+	enc.visited = enc.visited[:0]
+	clear(enc.hexDumps)
+	enc.hexDumps = enc.hexDumps[:0]
+	clear(enc.keyActions)
+	clear(enc.seenKeys)
+	clear(enc.fieldKeys)
+	clear(enc.subKeys)
+	enc.recorder.enc = nil
+	enc.keyFilter = nil
 	jsonPool.Put(enc)
 }
 
 func (enc *JsonEncoder) Encode(ent entry, fields []Field) (ret *Buffer, err error) {
+	enc.loadPrefix()
 	nenc := enc.clone()
 	defer putJsonEncoder(nenc)
 
 	nenc.writeBeginObject()
 	nenc.writePromptFields(&ent)
-	if nenc.writePrefixFields() {
-		nenc.writeSplitComma()
+	if nenc.dupKeys.Policy != DuplicateKeepAll {
+		nenc.resolveDuplicateKeys(fields, true)
 	}
+	// the msg stays at the top level even if the preFields open a namespace
+	nenc.writePrefixFields(0, nenc.prefixNamespace)
 	nenc.writeMsg(ent.message)
+	nenc.writePrefixFields(nenc.prefixNamespace, len(nenc.prefixEnds))
 
-	n := len(fields)
-	if n == 0 {
-		nenc.writeEndObject()
-		ret = nenc.buf
+	if ent.encoded != nil {
+		nenc.appendEncoded(ent.encoded)
+	} else if err = nenc.writeFields(fields); err != nil {
+		bufPool.Put(nenc.buf)
 		return
 	}
-	nenc.writeSplitComma()
-
-	for i := 0; i < n; i++ {
-		if err = nenc.writeField(&fields[i]); err != nil {
-			bufPool.Put(nenc.buf)
-			return
-		}
-		if i+1 != n {
-			nenc.writeSplitComma()
-		}
+	nenc.closeNamespaces(0)
+	if ent.stack != nil {
+		nenc.writeStacktrace(ent.stack)
 	}
 	nenc.writeEndObject()
 	ret = nenc.buf
 	return
 }
 
-func (enc *JsonEncoder) writeBeginObject() { enc.buf.AppendByte('{') }
+func (enc *JsonEncoder) writeBeginObject() {
+	enc.buf.AppendByte('{')
+	enc.depth++
+}
 
-func (enc *JsonEncoder) writeEndObject() { enc.buf.AppendByte('}') }
+func (enc *JsonEncoder) writeEndObject() {
+	enc.buf.AppendByte('}')
+	enc.depth--
+}
 
-func (enc *JsonEncoder) writeBeginArray() { enc.buf.AppendByte('[') }
+func (enc *JsonEncoder) writeBeginArray() {
+	enc.buf.AppendByte('[')
+	enc.depth++
+}
 
-func (enc *JsonEncoder) writeEndArray() { enc.buf.AppendByte(']') }
+func (enc *JsonEncoder) writeEndArray() {
+	enc.buf.AppendByte(']')
+	enc.depth--
+}
 
 func (enc *JsonEncoder) writePromptFields(ent *entry) {
 	if enc.levelF.enable {
 		enc.levelF.AppendField(enc, ent.level)
-		enc.writeSplitComma()
 	}
 	if enc.timeF.enable {
 		enc.timeF.AppendField(enc, ent.time)
-		enc.writeSplitComma()
 	}
 	if enc.callerF.enable {
 		enc.callerF.AppendField(enc)
-		enc.writeSplitComma()
 	}
 }
 
 func (enc *JsonEncoder) writeMsg(msg string) {
-	enc.writeFieldKey(enc.msgKey)
-	enc.writeSplitColon()
-	enc.writeFieldString(msg)
+	enc.writeKey(enc.msgKey)
+	enc.writeLimitedString(msg, enc.msgLimit(2))
 }
 
-func (enc *JsonEncoder) writePrefixFields() bool {
-	n := len(enc.preFields)
-	if n == 0 {
-		return false
+// writePrefixFields writes the cached preFields in the range [from, to), the
+// preFields exceeding the max entry size are dropped.
+func (enc *JsonEncoder) writePrefixFields(from, to int) {
+	if from >= to {
+		return
 	}
-	for i := 0; i < n; i++ {
-		enc.writeField(&enc.preFields[i])
-		if i+1 != n {
-			enc.writeSplitComma()
+	if to == len(enc.prefixEnds) {
+		enc.openNamespaces += enc.prefixNamespaces
+		enc.depth += enc.prefixNamespaces
+	}
+	resolved := enc.dupKeys.Policy != DuplicateKeepAll
+	limit := enc.limits.MaxEntrySize
+	if !resolved && (limit <= 0 || enc.truncated == 0 && enc.buf.Len()+enc.prefixLen(from, to) <= limit) {
+		enc.writePrefixSpan(from, to)
+		return
+	}
+	for i := from; i < to; i++ {
+		start := enc.buf.Len()
+		if resolved {
+			enc.writeResolvedPrefixField(i)
+		} else {
+			enc.writePrefixSpan(i, i+1)
+		}
+		if enc.dropOverflow(start) {
+			// the namespaces opened by the dropped field are counted already
+			opened := enc.prefixOpens[i]
+			if i > 0 {
+				opened -= enc.prefixOpens[i-1]
+			}
+			enc.openNamespaces -= opened
+			enc.depth -= opened
+		}
+	}
+}
+
+// prefixLen returns the length of the encoded preFields in the range [from, to).
+func (enc *JsonEncoder) prefixLen(from, to int) int {
+	if from > 0 {
+		return enc.prefixEnds[to-1] - enc.prefixEnds[from-1]
+	}
+	return enc.prefixEnds[to-1]
+}
+
+// writePrefixSpan copies the encoded preFields in the range [from, to) from the cache.
+func (enc *JsonEncoder) writePrefixSpan(from, to int) {
+	start := 0
+	if from > 0 {
+		start = enc.prefixEnds[from-1]
+	}
+	span := enc.prefix[start:enc.prefixEnds[to-1]]
+	if len(span) > 0 && span[0] == ',' {
+		span = span[1:]
+	}
+	if len(span) > 0 {
+		enc.addElementSeparator()
+		enc.buf.AppendBytes(span)
+	}
+}
+
+// OpenNamespace opens a nested object with the key, the following key-values are
+// written into it until the enclosing object ends.
+func (enc *JsonEncoder) OpenNamespace(key string) {
+	enc.writeKey(key)
+	enc.writeBeginObject()
+	enc.openNamespaces++
+}
+
+// closeNamespaces closes the namespaces opened after there were n open ones.
+func (enc *JsonEncoder) closeNamespaces(n int) {
+	for ; enc.openNamespaces > n; enc.openNamespaces-- {
+		enc.writeEndObject()
+	}
+}
+
+// writeFields writes the fields of a log call after the preFields, the fields
+// exceeding the max entry size are dropped and replaced by a marker.
+func (enc *JsonEncoder) writeFields(fields []Field) (err error) {
+	resolved := enc.dupKeys.Policy != DuplicateKeepAll
+	for i := 0; i < len(fields); i++ {
+		start, open, depth := enc.buf.Len(), enc.openNamespaces, enc.depth
+		if resolved {
+			err = enc.writeResolvedField(&fields[i], &enc.keyActions[len(enc.preFields)+i])
+		} else {
+			err = enc.writeField(&fields[i])
+		}
+		if err != nil {
+			return
+		}
+		if enc.dropOverflow(start) {
+			enc.openNamespaces, enc.depth = open, depth
 		}
 	}
-	return true
+	if enc.truncated > 0 {
+		enc.writeKey("…")
+		enc.writeTruncatedMarker(enc.truncated, "bytes")
+	}
+	return
 }
 
 func (enc *JsonEncoder) writeQuote() {
@@ -127,6 +323,28 @@ func (enc *JsonEncoder) writeSplitColon() {
 	enc.buf.AppendByte(':')
 }
 
+// addElementSeparator writes a comma unless it's the first element of an
+// object or array, so that the fields which write nothing leave no dangling comma.
+func (enc *JsonEncoder) addElementSeparator() {
+	last := enc.buf.Len() - 1
+	if last < 0 {
+		return
+	}
+	switch enc.buf.Bytes()[last] {
+	case '{', '[', ':', ',':
+		return
+	default:
+		enc.writeSplitComma()
+	}
+}
+
+// writeKey writes the separator, the key and the colon of an object member.
+func (enc *JsonEncoder) writeKey(key string) {
+	enc.addElementSeparator()
+	enc.writeFieldKey(key)
+	enc.writeSplitColon()
+}
+
 func (enc *JsonEncoder) writeFieldValue(field *Field) {
 	switch field.Type {
 	case StringType:
@@ -175,6 +393,32 @@ func (enc *JsonEncoder) writeFieldValue(field *Field) {
 		enc.writeFieldError(field.AnyValue.(error))
 	case ObjectType:
 		enc.writeFieldObject(field.AnyValue.([]Field))
+	case BinaryType:
+		enc.writeFieldBinary(field.Key, unsafe.Slice(unsafe.StringData(field.StringValue), len(field.StringValue)))
+	case ByteStringType:
+		enc.writeFieldByteString(field.StringValue)
+	case StringsType, BoolsType, IntsType, Int8sType, Int16sType, Int32sType, Int64sType,
+		UintsType, Uint8sType, Uint16sType, Uint32sType, Uint64sType,
+		Float32sType, Float64sType, TimesType, DurationsType:
+		enc.writeFieldSlice(field)
+	case IPType:
+		enc.writeFieldIP(unsafe.Slice(unsafe.StringData(field.StringValue), len(field.StringValue)))
+	case AddrType:
+		enc.writeFieldAddr(field.AnyValue.(netip.Addr))
+	case AddrsType:
+		enc.writeFieldAddrs(fieldSlice[netip.Addr](field))
+	case AddrPortType:
+		enc.writeFieldAddrPort(field.AnyValue.(netip.AddrPort))
+	case PrefixType:
+		enc.writeFieldPrefix(field.AnyValue.(netip.Prefix))
+	case HardwareAddrType:
+		enc.writeFieldHardwareAddr(unsafe.Slice(unsafe.StringData(field.StringValue), len(field.StringValue)))
+	case URLType:
+		enc.writeFieldURL(field.AnyValue.(*url.URL))
+	case LazyType:
+		enc.writeFieldAny(field.AnyValue.(func() any)())
+	case LazyObjectType:
+		enc.writeFieldObject(field.AnyValue.(func() []Field)())
 	case ArrayType:
 		enc.writeFieldArray(field.AnyValue)
 	case NilType:
@@ -185,96 +429,148 @@ func (enc *JsonEncoder) writeFieldValue(field *Field) {
 }
 
 func (enc *JsonEncoder) writeField(field *Field) error {
-	if field.Type == NoneType {
+	switch field.Type {
+	case NoneType:
 		return errInvalidFieldType
+	case NamespaceType:
+		enc.OpenNamespace(field.Key)
+		return nil
+	case ErrorType:
+		if field.AnyValue != nil {
+			enc.AddError(field.Key, field.AnyValue.(error))
+			return nil
+		}
+	case InlineType:
+		if err := field.AnyValue.(ObjectMarshaler).MarshalLogObject(enc); err != nil {
+			enc.AddString("error", err.Error())
+		}
+		return nil
+	case ObjectMarshalerType:
+		if err := enc.AddObject(field.Key, field.AnyValue.(ObjectMarshaler)); err != nil {
+			enc.AddString(field.Key+"Error", err.Error())
+		}
+		return nil
+	case ArrayMarshalerType:
+		if err := enc.AddArray(field.Key, field.AnyValue.(ArrayMarshaler)); err != nil {
+			enc.AddString(field.Key+"Error", err.Error())
+		}
+		return nil
+	}
+	if enc.addKey(field.Key) {
+		enc.writeFieldValue(field)
 	}
-	enc.writeFieldKey(field.Key)
-	enc.writeSplitColon()
-	enc.writeFieldValue(field)
 	return nil
 }
 
-func (enc *JsonEncoder) colorEnabled() bool { return enc.colors.enable }
+func (enc *JsonEncoder) colorEnabled() bool { return enc.colors.enable && !enc.plain }
 
-func (enc *JsonEncoder) writeFieldKey(key string) {
-	enc.writeQuote()
-	if enc.escapeQuote {
-		writeFieldWrapper(enc, enc.colors.attr.KeyColor, func(buf *Buffer) { appendQuoteString(buf, key) })
-	} else {
-		writeFieldWrapper(enc, enc.colors.attr.KeyColor, func(buf *Buffer) { buf.AppendString(key) })
+// beginColor and endColor surround a value with the color escape sequences,
+// \x1b[30mAAAAAAAAA\x1b[0m
+func (enc *JsonEncoder) beginColor(color ColorAttr) {
+	if enc.colorEnabled() {
+		appendColorBegin(enc.buf, color)
 	}
-	enc.writeQuote()
 }
 
-func (enc *JsonEncoder) writeFieldString(value string) {
-	enc.writeQuote()
-	if enc.escapeQuote {
-		writeFieldWrapper(enc, enc.colors.attr.StringColor, func(buf *Buffer) { appendQuoteString(buf, value) })
+func (enc *JsonEncoder) endColor() {
+	if enc.colorEnabled() {
+		appendColorEnd(enc.buf)
+	}
+}
+
+func (enc *JsonEncoder) writeRawString(value string) {
+	if enc.escapeQuote && !enc.plain {
+		appendQuoteString(enc.buf, value)
 	} else {
-		writeFieldWrapper(enc, enc.colors.attr.StringColor, func(buf *Buffer) { buf.AppendString(value) })
+		enc.buf.AppendString(value)
 	}
+}
+
+func (enc *JsonEncoder) writeFieldKey(key string) {
+	enc.writeQuote()
+	enc.beginColor(enc.colors.attr.KeyColor)
+	enc.writeRawString(key)
+	enc.endColor()
 	enc.writeQuote()
 }
 
-func (enc *JsonEncoder) writeFieldBool(value bool) {
-	writeFieldWrapper(enc, enc.colors.attr.BooleanColor, func(buf *Buffer) { buf.AppendBool(value) })
+func (enc *JsonEncoder) writeFieldString(value string) {
+	enc.writeLimitedString(value, enc.limits.MaxStringLength)
 }
 
-func writeFieldWrapper(enc *JsonEncoder, color ColorAttr, appendFn func(*Buffer)) {
-	if enc.colorEnabled() {
-		// \x1b[30mAAAAAAAAA\x1b[0m
-		appendColorWithFunc(enc.buf, color, appendFn)
+// writeLimitedString writes the string value truncated to the limit bytes if
+// the limit > 0.
+func (enc *JsonEncoder) writeLimitedString(value string, limit int) {
+	if enc.redactor != nil && len(enc.redactor.patterns) > 0 {
+		value = enc.redactor.replace(value)
+	}
+	if limit > 0 && len(value) > limit {
+		value, n := truncateString(value, limit)
+		enc.writeQuote()
+		enc.beginColor(enc.colors.attr.StringColor)
+		enc.writeRawString(value)
+		appendTruncatedMarker(enc.buf, n, "bytes")
+		enc.endColor()
+		enc.writeQuote()
 		return
 	}
-	appendFn(enc.buf)
+	enc.writeQuotedString(value)
 }
 
-func (enc *JsonEncoder) writeFieldInt8(value int8) {
-	writeFieldWrapper(enc, enc.colors.attr.NumberColor, func(buf *Buffer) { buf.AppendInt(int64(value)) })
+// writeQuotedString writes the string value without redaction, the value
+// doesn't escape so that the temporary strings can stay on the stack.
+func (enc *JsonEncoder) writeQuotedString(value string) {
+	enc.writeQuote()
+	enc.beginColor(enc.colors.attr.StringColor)
+	enc.writeRawString(value)
+	enc.endColor()
+	enc.writeQuote()
 }
 
-func (enc *JsonEncoder) writeFieldInt16(value int16) {
-	writeFieldWrapper(enc, enc.colors.attr.NumberColor, func(buf *Buffer) { buf.AppendInt(int64(value)) })
+func (enc *JsonEncoder) writeFieldBool(value bool) {
+	enc.beginColor(enc.colors.attr.BooleanColor)
+	enc.buf.AppendBool(value)
+	enc.endColor()
 }
 
-func (enc *JsonEncoder) writeFieldInt32(value int32) {
-	writeFieldWrapper(enc, enc.colors.attr.NumberColor, func(buf *Buffer) { buf.AppendInt(int64(value)) })
-}
+func (enc *JsonEncoder) writeFieldInt8(value int8) { enc.writeFieldInt64(int64(value)) }
+
+func (enc *JsonEncoder) writeFieldInt16(value int16) { enc.writeFieldInt64(int64(value)) }
+
+func (enc *JsonEncoder) writeFieldInt32(value int32) { enc.writeFieldInt64(int64(value)) }
 
 func (enc *JsonEncoder) writeFieldInt64(value int64) {
-	writeFieldWrapper(enc, enc.colors.attr.NumberColor, func(buf *Buffer) { buf.AppendInt(value) })
+	enc.beginColor(enc.colors.attr.NumberColor)
+	enc.buf.AppendInt(value)
+	enc.endColor()
 }
 
-func (enc *JsonEncoder) writeFieldInt(value int) {
-	writeFieldWrapper(enc, enc.colors.attr.NumberColor, func(buf *Buffer) { buf.AppendInt(int64(value)) })
-}
+func (enc *JsonEncoder) writeFieldInt(value int) { enc.writeFieldInt64(int64(value)) }
 
-func (enc *JsonEncoder) writeFieldUint8(value uint8) {
-	writeFieldWrapper(enc, enc.colors.attr.NumberColor, func(buf *Buffer) { buf.AppendUint(uint64(value)) })
-}
+func (enc *JsonEncoder) writeFieldUint8(value uint8) { enc.writeFieldUint64(uint64(value)) }
 
-func (enc *JsonEncoder) writeFieldUint16(value uint16) {
-	writeFieldWrapper(enc, enc.colors.attr.NumberColor, func(buf *Buffer) { buf.AppendUint(uint64(value)) })
-}
+func (enc *JsonEncoder) writeFieldUint16(value uint16) { enc.writeFieldUint64(uint64(value)) }
 
-func (enc *JsonEncoder) writeFieldUint32(value uint32) {
-	writeFieldWrapper(enc, enc.colors.attr.NumberColor, func(buf *Buffer) { buf.AppendUint(uint64(value)) })
-}
+func (enc *JsonEncoder) writeFieldUint32(value uint32) { enc.writeFieldUint64(uint64(value)) }
 
 func (enc *JsonEncoder) writeFieldUint64(value uint64) {
-	writeFieldWrapper(enc, enc.colors.attr.NumberColor, func(buf *Buffer) { buf.AppendUint(value) })
+	enc.beginColor(enc.colors.attr.NumberColor)
+	enc.buf.AppendUint(value)
+	enc.endColor()
 }
 
-func (enc *JsonEncoder) writeFieldUint(value uint) {
-	writeFieldWrapper(enc, enc.colors.attr.NumberColor, func(buf *Buffer) { buf.AppendUint(uint64(value)) })
-}
+func (enc *JsonEncoder) writeFieldUint(value uint) { enc.writeFieldUint64(uint64(value)) }
 
 func (enc *JsonEncoder) writeFieldFloat32(value float32) {
-	writeFieldWrapper(enc, enc.colors.attr.NumberColor, func(buf *Buffer) { buf.AppendFloat(float64(value), 32) })
+	enc.beginColor(enc.colors.attr.NumberColor)
+	enc.buf.AppendFloat(float64(value), 32)
+	enc.endColor()
 }
 
 func (enc *JsonEncoder) writeFieldFloat64(value float64) {
-	writeFieldWrapper(enc, enc.colors.attr.NumberColor, func(buf *Buffer) { buf.AppendFloat(value, 64) })
+	enc.beginColor(enc.colors.attr.NumberColor)
+	enc.buf.AppendFloat(value, 64)
+	enc.endColor()
 }
 
 func (enc *JsonEncoder) writeFieldTime(value time.Time) {
@@ -282,7 +578,16 @@ func (enc *JsonEncoder) writeFieldTime(value time.Time) {
 }
 
 func (enc *JsonEncoder) writeFieldDuration(value time.Duration) {
-	enc.writeFieldString(value.String())
+	switch enc.durationEnc {
+	case DurationNanos:
+		enc.writeFieldInt64(int64(value))
+	case DurationMillis:
+		enc.writeFieldInt64(value.Milliseconds())
+	case DurationSeconds:
+		enc.writeFieldFloat64(value.Seconds())
+	default:
+		enc.writeQuotedString(value.String())
+	}
 }
 
 func (enc *JsonEncoder) writeFieldError(value error) {
@@ -294,154 +599,178 @@ func (enc *JsonEncoder) writeFieldError(value error) {
 }
 
 func (enc *JsonEncoder) writeMapObjectForAnyValue(value map[string]any) {
-	enc.writeBeginObject()
-	// the key-values are unsorted!!!
-	i, n := 0, len(value)
-	for k, v := range value {
-		enc.writeFieldKey(k)
-		enc.writeSplitColon()
-		enc.writeFieldAny(v)
-		if i+1 != n {
-			enc.writeSplitComma()
-		}
-		i++
+	if value == nil {
+		writeMapObject(enc, value, (*JsonEncoder).writeFieldAny)
+		return
 	}
-	enc.writeEndObject()
+	if !enc.enterRef(mapRef(reflect.ValueOf(value))) {
+		return
+	}
+	writeMapObject(enc, value, (*JsonEncoder).writeFieldAny)
+	enc.leaveRef()
 }
 
 func (enc *JsonEncoder) writeMapObjectForStringValue(value map[string]string) {
-	enc.writeBeginObject()
-	// the key-values are unsorted!!!
-	i, n := 0, len(value)
-	for k, v := range value {
-		enc.writeFieldKey(k)
-		enc.writeSplitColon()
-		enc.writeFieldString(v)
-		if i+1 != n {
-			enc.writeSplitComma()
-		}
-		i++
-	}
-	enc.writeEndObject()
+	writeMapObject(enc, value, (*JsonEncoder).writeFieldString)
 }
 
 func (enc *JsonEncoder) writeMapObjectForMultipleValue(value map[string][]string) {
+	writeMapObject(enc, value, (*JsonEncoder).writeFieldStrings)
+}
+
+func (enc *JsonEncoder) writeFieldStrings(value []string) {
+	writeFieldArrayListFor(enc, value, (*JsonEncoder).writeFieldString)
+}
+
+func writeMapObject[V any](enc *JsonEncoder, value map[string]V, wf func(*JsonEncoder, V)) {
+	if !enc.canNest() {
+		return
+	}
 	enc.writeBeginObject()
-	// the key-values are unsorted!!!
-	i, n := 0, len(value)
-	for k, v := range value {
-		enc.writeFieldKey(k)
-		enc.writeSplitColon()
-		enc.writeFieldArray(v)
-		if i+1 != n {
-			enc.writeSplitComma()
+	n := enc.maxObjectKeys(len(value))
+	if enc.sortMapKeys {
+		for _, k := range slices.Sorted(maps.Keys(value))[:n] {
+			if enc.addKey(k) {
+				wf(enc, value[k])
+			}
+		}
+	} else {
+		// the key-values are unsorted!!!
+		i := 0
+		for k, v := range value {
+			if i == n {
+				break
+			}
+			i++
+			if enc.addKey(k) {
+				wf(enc, v)
+			}
 		}
-		i++
+	}
+	if n < len(value) {
+		enc.writeTruncatedKeys(len(value) - n)
 	}
 	enc.writeEndObject()
 }
 
+func (enc *JsonEncoder) writeFieldArray(value any) {
+	// the elements of ArrayT[uint8] are numbers, unlike the []byte values
+	if v, ok := value.([]uint8); ok {
+		writeFieldArrayListFor(enc, v, (*JsonEncoder).writeFieldUint8)
+		return
+	}
+	enc.writeFieldAny(value)
+}
+
 func (enc *JsonEncoder) writeFieldObject(value []Field) {
+	if !enc.canNest() {
+		return
+	}
+	n := enc.openNamespaces
 	enc.writeBeginObject()
-	n := len(value)
-	for i := 0; i < n; i++ {
-		if err := enc.writeField(&value[i]); err != nil {
-			continue
-		}
-		if i+1 != n {
-			enc.writeSplitComma()
-		}
+	keys := enc.maxObjectKeys(len(value))
+	for i := 0; i < keys; i++ {
+		enc.writeField(&value[i])
+	}
+	enc.closeNamespaces(n)
+	if keys < len(value) {
+		enc.writeTruncatedKeys(len(value) - keys)
 	}
 	enc.writeEndObject()
 }
 
 func (enc *JsonEncoder) writeFieldSingleObject(value Field) {
+	if !enc.canNest() {
+		return
+	}
+	n := enc.openNamespaces
 	enc.writeBeginObject()
 	enc.writeField(&value)
+	enc.closeNamespaces(n)
 	enc.writeEndObject()
 }
 
-func (enc *JsonEncoder) writeFieldArray(value any) {
-	enc.writeFieldAny(value)
-}
-
 func (enc *JsonEncoder) writeFieldNil() {
-	writeFieldWrapper(enc, enc.colors.attr.StringColor, func(buf *Buffer) { buf.AppendString("null") })
+	enc.beginColor(enc.colors.attr.StringColor)
+	enc.buf.AppendString("null")
+	enc.endColor()
 }
 
-func writeFieldArrayListFor[T any](value []T, enc *JsonEncoder, wf func(T), lf func()) {
-	enc.writeBeginArray()
-	for i := 0; i < len(value); i++ {
-		wf(value[i])
-		if i+1 != len(value) {
-			lf()
-		}
+// writeFieldArrayListFor takes a method expression rather than a closure, so
+// that the array encoding doesn't allocate.
+func writeFieldArrayListFor[T any](enc *JsonEncoder, value []T, wf func(*JsonEncoder, T)) {
+	if !enc.canNest() {
+		return
 	}
-	enc.writeEndArray()
-}
-
-func writeFieldArrayListForReflectValue(value reflect.Value, enc *JsonEncoder, wf func(any), lf func()) {
 	enc.writeBeginArray()
-	n := value.Len()
+	n := enc.maxArrayElements(len(value))
 	for i := 0; i < n; i++ {
-		idxv := value.Index(i)
-		if !idxv.CanInterface() {
-			continue
-		}
-		wf(idxv.Interface())
-		if i+1 != n {
-			lf()
+		wf(enc, value[i])
+		if i+1 != len(value) {
+			enc.writeSplitComma()
 		}
 	}
+	if n < len(value) {
+		enc.writeTruncatedMarker(len(value)-n, "elements")
+	}
 	enc.writeEndArray()
 }
 
 func (enc *JsonEncoder) writeFieldAny(value any) {
 	switch v := value.(type) {
 	case []string:
-		writeFieldArrayListFor(v, enc, func(s string) { enc.writeFieldString(s) }, enc.writeSplitComma)
+		writeFieldArrayListFor(enc, v, (*JsonEncoder).writeFieldString)
 	case []bool:
-		writeFieldArrayListFor(v, enc, func(s bool) { enc.writeFieldBool(s) }, enc.writeSplitComma)
+		writeFieldArrayListFor(enc, v, (*JsonEncoder).writeFieldBool)
 	case []int8:
-		writeFieldArrayListFor(v, enc, func(s int8) { enc.writeFieldInt8(s) }, enc.writeSplitComma)
+		writeFieldArrayListFor(enc, v, (*JsonEncoder).writeFieldInt8)
 	case []int16:
-		writeFieldArrayListFor(v, enc, func(s int16) { enc.writeFieldInt16(s) }, enc.writeSplitComma)
+		writeFieldArrayListFor(enc, v, (*JsonEncoder).writeFieldInt16)
 	case []int32:
-		writeFieldArrayListFor(v, enc, func(s int32) { enc.writeFieldInt32(s) }, enc.writeSplitComma)
+		writeFieldArrayListFor(enc, v, (*JsonEncoder).writeFieldInt32)
 	case []int64:
-		writeFieldArrayListFor(v, enc, func(s int64) { enc.writeFieldInt64(s) }, enc.writeSplitComma)
+		writeFieldArrayListFor(enc, v, (*JsonEncoder).writeFieldInt64)
 	case []int:
-		writeFieldArrayListFor(v, enc, func(s int) { enc.writeFieldInt(s) }, enc.writeSplitComma)
+		writeFieldArrayListFor(enc, v, (*JsonEncoder).writeFieldInt)
 	case []uint8:
-		writeFieldArrayListFor(v, enc, func(s uint8) { enc.writeFieldUint8(s) }, enc.writeSplitComma)
+		if v == nil {
+			enc.writeFieldNil()
+		} else {
+			enc.writeFieldBinary("", v)
+		}
 	case []uint16:
-		writeFieldArrayListFor(v, enc, func(s uint16) { enc.writeFieldUint16(s) }, enc.writeSplitComma)
+		writeFieldArrayListFor(enc, v, (*JsonEncoder).writeFieldUint16)
 	case []uint32:
-		writeFieldArrayListFor(v, enc, func(s uint32) { enc.writeFieldUint32(s) }, enc.writeSplitComma)
+		writeFieldArrayListFor(enc, v, (*JsonEncoder).writeFieldUint32)
 	case []uint64:
-		writeFieldArrayListFor(v, enc, func(s uint64) { enc.writeFieldUint64(s) }, enc.writeSplitComma)
+		writeFieldArrayListFor(enc, v, (*JsonEncoder).writeFieldUint64)
 	case []uint:
-		writeFieldArrayListFor(v, enc, func(s uint) { enc.writeFieldUint(s) }, enc.writeSplitComma)
+		writeFieldArrayListFor(enc, v, (*JsonEncoder).writeFieldUint)
 	case []float32:
-		writeFieldArrayListFor(v, enc, func(s float32) { enc.writeFieldFloat32(s) }, enc.writeSplitComma)
+		writeFieldArrayListFor(enc, v, (*JsonEncoder).writeFieldFloat32)
 	case []float64:
-		writeFieldArrayListFor(v, enc, func(s float64) { enc.writeFieldFloat64(s) }, enc.writeSplitComma)
+		writeFieldArrayListFor(enc, v, (*JsonEncoder).writeFieldFloat64)
 	case []time.Time:
-		writeFieldArrayListFor(v, enc, func(s time.Time) { enc.writeFieldTime(s) }, enc.writeSplitComma)
+		writeFieldArrayListFor(enc, v, (*JsonEncoder).writeFieldTime)
 	case []time.Duration:
-		writeFieldArrayListFor(v, enc, func(s time.Duration) { enc.writeFieldDuration(s) }, enc.writeSplitComma)
+		writeFieldArrayListFor(enc, v, (*JsonEncoder).writeFieldDuration)
 	case []error:
-		writeFieldArrayListFor(v, enc, func(s error) { enc.writeFieldError(s) }, enc.writeSplitComma)
+		writeFieldArrayListFor(enc, v, (*JsonEncoder).writeFieldError)
 	case []map[string]any:
-		writeFieldArrayListFor(v, enc, func(s map[string]any) { enc.writeMapObjectForAnyValue(s) }, enc.writeSplitComma)
+		writeFieldArrayListFor(enc, v, (*JsonEncoder).writeMapObjectForAnyValue)
 	case []map[string]string:
-		writeFieldArrayListFor(v, enc, func(s map[string]string) { enc.writeMapObjectForStringValue(s) }, enc.writeSplitComma)
+		writeFieldArrayListFor(enc, v, (*JsonEncoder).writeMapObjectForStringValue)
 	case []map[string][]string:
-		writeFieldArrayListFor(v, enc, func(s map[string][]string) { enc.writeMapObjectForMultipleValue(s) }, enc.writeSplitComma)
+		writeFieldArrayListFor(enc, v, (*JsonEncoder).writeMapObjectForMultipleValue)
 	case []Field:
 		enc.writeFieldObject(v)
 	case []any:
-		writeFieldArrayListFor(v, enc, func(s any) { enc.writeFieldAny(s) }, enc.writeSplitComma)
+		if len(v) == 0 || enc.enterRef(sliceRef(v)) {
+			writeFieldArrayListFor(enc, v, (*JsonEncoder).writeFieldAny)
+			if len(v) > 0 {
+				enc.leaveRef()
+			}
+		}
 	case string:
 		enc.writeFieldString(v)
 	case bool:
@@ -480,6 +809,24 @@ func (enc *JsonEncoder) writeFieldAny(value any) {
 		enc.writeMapObjectForStringValue(v)
 	case map[string][]string:
 		enc.writeMapObjectForMultipleValue(v)
+	case net.IP:
+		enc.writeFieldIP(v)
+	case net.HardwareAddr:
+		enc.writeFieldHardwareAddr(v)
+	case netip.Addr:
+		enc.writeFieldAddr(v)
+	case []netip.Addr:
+		enc.writeFieldAddrs(v)
+	case netip.AddrPort:
+		enc.writeFieldAddrPort(v)
+	case netip.Prefix:
+		enc.writeFieldPrefix(v)
+	case *url.URL:
+		enc.writeFieldURL(v)
+	case ObjectMarshaler:
+		enc.writeMarshalObject(v)
+	case ArrayMarshaler:
+		enc.writeMarshalArray(v)
 	case error:
 		enc.writeFieldError(v)
 	case Field:
@@ -487,15 +834,180 @@ func (enc *JsonEncoder) writeFieldAny(value any) {
 	case nil:
 		enc.writeFieldNil()
 	default:
-		if enc.reflectValue && reflect.TypeOf(value).Kind() == reflect.Slice {
-			valueOf := reflect.ValueOf(value)
-			writeFieldArrayListForReflectValue(valueOf, enc, enc.writeFieldAny, enc.writeSplitComma)
+		if enc.reflectValue {
+			enc.writeReflectValue(reflect.ValueOf(value))
 		} else {
 			enc.writeFieldString(fmt.Sprintf("%v", value))
 		}
 	}
 }
 
+func (enc *JsonEncoder) writeMarshalObject(value ObjectMarshaler) error {
+	if !enc.canNest() {
+		return nil
+	}
+	n := enc.openNamespaces
+	enc.writeBeginObject()
+	err := value.MarshalLogObject(enc)
+	enc.closeNamespaces(n)
+	enc.writeEndObject()
+	return err
+}
+
+func (enc *JsonEncoder) writeMarshalArray(value ArrayMarshaler) error {
+	if !enc.canNest() {
+		return nil
+	}
+	enc.writeBeginArray()
+	err := value.MarshalLogArray(enc)
+	enc.writeEndArray()
+	return err
+}
+
+// ObjectEncoder implementation
+
+func (enc *JsonEncoder) AddString(key, value string) {
+	if enc.addKey(key) {
+		enc.writeFieldString(value)
+	}
+}
+
+func (enc *JsonEncoder) AddBool(key string, value bool) {
+	if enc.addKey(key) {
+		enc.writeFieldBool(value)
+	}
+}
+
+func (enc *JsonEncoder) AddInt(key string, value int) {
+	if enc.addKey(key) {
+		enc.writeFieldInt(value)
+	}
+}
+
+func (enc *JsonEncoder) AddInt64(key string, value int64) {
+	if enc.addKey(key) {
+		enc.writeFieldInt64(value)
+	}
+}
+
+func (enc *JsonEncoder) AddUint64(key string, value uint64) {
+	if enc.addKey(key) {
+		enc.writeFieldUint64(value)
+	}
+}
+
+func (enc *JsonEncoder) AddFloat64(key string, value float64) {
+	if enc.addKey(key) {
+		enc.writeFieldFloat64(value)
+	}
+}
+
+func (enc *JsonEncoder) AddTime(key string, value time.Time) {
+	if enc.addKey(key) {
+		enc.writeFieldTime(value)
+	}
+}
+
+func (enc *JsonEncoder) AddDuration(key string, value time.Duration) {
+	if enc.addKey(key) {
+		enc.writeFieldDuration(value)
+	}
+}
+
+func (enc *JsonEncoder) AddError(key string, value error) {
+	if !enc.addKey(key) {
+		return
+	}
+	enc.writeFieldError(value)
+	if value != nil && enc.errorOpt != (ErrorOption{}) {
+		enc.writeErrorDetails(key, value)
+	}
+}
+
+func (enc *JsonEncoder) AddAny(key string, value any) {
+	if enc.addKey(key) {
+		enc.writeFieldAny(value)
+	}
+}
+
+func (enc *JsonEncoder) AddField(field Field) { enc.writeField(&field) }
+
+func (enc *JsonEncoder) AddObject(key string, value ObjectMarshaler) error {
+	if !enc.addKey(key) {
+		return nil
+	}
+	return enc.writeMarshalObject(value)
+}
+
+func (enc *JsonEncoder) AddArray(key string, value ArrayMarshaler) error {
+	if !enc.addKey(key) {
+		return nil
+	}
+	return enc.writeMarshalArray(value)
+}
+
+// ArrayEncoder implementation
+
+func (enc *JsonEncoder) AppendString(value string) {
+	enc.addElementSeparator()
+	enc.writeFieldString(value)
+}
+
+func (enc *JsonEncoder) AppendBool(value bool) {
+	enc.addElementSeparator()
+	enc.writeFieldBool(value)
+}
+
+func (enc *JsonEncoder) AppendInt(value int) {
+	enc.addElementSeparator()
+	enc.writeFieldInt(value)
+}
+
+func (enc *JsonEncoder) AppendInt64(value int64) {
+	enc.addElementSeparator()
+	enc.writeFieldInt64(value)
+}
+
+func (enc *JsonEncoder) AppendUint64(value uint64) {
+	enc.addElementSeparator()
+	enc.writeFieldUint64(value)
+}
+
+func (enc *JsonEncoder) AppendFloat64(value float64) {
+	enc.addElementSeparator()
+	enc.writeFieldFloat64(value)
+}
+
+func (enc *JsonEncoder) AppendTime(value time.Time) {
+	enc.addElementSeparator()
+	enc.writeFieldTime(value)
+}
+
+func (enc *JsonEncoder) AppendDuration(value time.Duration) {
+	enc.addElementSeparator()
+	enc.writeFieldDuration(value)
+}
+
+func (enc *JsonEncoder) AppendError(value error) {
+	enc.addElementSeparator()
+	enc.writeFieldError(value)
+}
+
+func (enc *JsonEncoder) AppendAny(value any) {
+	enc.addElementSeparator()
+	enc.writeFieldAny(value)
+}
+
+func (enc *JsonEncoder) AppendObject(value ObjectMarshaler) error {
+	enc.addElementSeparator()
+	return enc.writeMarshalObject(value)
+}
+
+func (enc *JsonEncoder) AppendArray(value ArrayMarshaler) error {
+	enc.addElementSeparator()
+	return enc.writeMarshalArray(value)
+}
+
 func appendQuoteString(buf *Buffer, value string) {
 	if len(value) == 0 {
 		return
@@ -1,5 +1,11 @@
 package logx
 
+import (
+	"fmt"
+	"strings"
+)
+
+// LevelType is the severity of the entries, ordered by the numeric values.
 type LevelType uint8
 
 const (
@@ -8,40 +14,55 @@ const (
 	LevelInfo
 	LevelWarn
 	LevelError
+	// logs like LevelError and panics in the development mode
+	LevelDPanic
 	LevelFatal
 	LevelPanic
 )
 
 var (
 	levelTypeLowerMap = map[LevelType]string{
-		LevelTrace: "trace",
-		LevelDebug: "debug",
-		LevelInfo:  "info",
-		LevelWarn:  "warn",
-		LevelError: "error",
-		LevelFatal: "fatal",
-		LevelPanic: "panic",
+		LevelTrace:  "trace",
+		LevelDebug:  "debug",
+		LevelInfo:   "info",
+		LevelWarn:   "warn",
+		LevelError:  "error",
+		LevelDPanic: "dpanic",
+		LevelFatal:  "fatal",
+		LevelPanic:  "panic",
 	}
 	levelTypeUpperMap = map[LevelType]string{
-		LevelTrace: "TRACE",
-		LevelDebug: "DEBUG",
-		LevelInfo:  "INFO",
-		LevelWarn:  "WARN",
-		LevelError: "ERROR",
-		LevelFatal: "FATAL",
-		LevelPanic: "PANIC",
+		LevelTrace:  "TRACE",
+		LevelDebug:  "DEBUG",
+		LevelInfo:   "INFO",
+		LevelWarn:   "WARN",
+		LevelError:  "ERROR",
+		LevelDPanic: "DPANIC",
+		LevelFatal:  "FATAL",
+		LevelPanic:  "PANIC",
 	}
 	levelTypeColorMap = map[LevelType]ColorAttr{
-		LevelTrace: MagentaAttr,
-		LevelDebug: HiCyanAttr,
-		LevelInfo:  GreenAttr,
-		LevelWarn:  YellowAttr,
-		LevelError: RedAttr,
-		LevelFatal: HiRedAttr,
-		LevelPanic: HiYellowAttr,
+		LevelTrace:  MagentaAttr,
+		LevelDebug:  HiCyanAttr,
+		LevelInfo:   GreenAttr,
+		LevelWarn:   YellowAttr,
+		LevelError:  RedAttr,
+		LevelDPanic: HiMagentaAttr,
+		LevelFatal:  HiRedAttr,
+		LevelPanic:  HiYellowAttr,
 	}
 )
 
+// ParseLevel parses a case-insensitive level name such as "info" or "WARN".
+func ParseLevel(s string) (LevelType, error) {
+	for level, name := range levelTypeLowerMap {
+		if strings.EqualFold(name, s) {
+			return level, nil
+		}
+	}
+	return 0, fmt.Errorf("logx: unknown level %q", s)
+}
+
 type LevelOption struct {
 	// level key, default: "level"
 	LevelKey string
@@ -56,8 +77,7 @@ type levelField struct {
 }
 
 func (lvl *levelField) AppendField(enc *JsonEncoder, level LevelType) {
-	enc.writeFieldKey(lvl.option.LevelKey)
-	enc.writeSplitColon()
+	enc.writeKey(lvl.option.LevelKey)
 	enc.writeQuote()
 	lvl.AppendPrimitive(enc.buf, level)
 	enc.writeQuote()
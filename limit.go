@@ -0,0 +1,136 @@
+package logx
+
+import (
+	"reflect"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// LimitOption bounds the size of the entries, the values exceeding the limits
+// are truncated with the markers such as "…(truncated 1234 bytes)". Zero means
+// no limit.
+type LimitOption struct {
+	// max bytes of a string value and the message
+	MaxStringLength int
+	// max elements of an array
+	MaxArrayElements int
+	// max keys of an object, excluding the fields of the entry
+	MaxObjectKeys int
+	// max nesting depth of the objects and arrays, including the namespaces
+	MaxDepth int
+	// max bytes of an entry, the msg is truncated and the fields of the logger
+	// and the log call exceeding it are dropped
+	MaxEntrySize int
+}
+
+// visitedRef identifies a map, slice or pointer being encoded, len is -1 for
+// maps and pointers.
+type visitedRef struct {
+	ptr unsafe.Pointer
+	len int
+}
+
+// truncateString cuts value to at most limit bytes at a rune boundary and
+// returns the number of the truncated bytes.
+func truncateString(value string, limit int) (string, int) {
+	if limit <= 0 || len(value) <= limit {
+		return value, 0
+	}
+	n := limit
+	for n > 0 && !utf8.RuneStart(value[n]) {
+		n--
+	}
+	return value[:n], len(value) - n
+}
+
+// msgLimit returns the max bytes of the msg, which is truncated to fit the max
+// entry size besides the max string length, leaving the room of the quotes.
+func (enc *JsonEncoder) msgLimit(quotes int) int {
+	limit := enc.limits.MaxStringLength
+	if size := enc.limits.MaxEntrySize; size > 0 {
+		if room := max(size-enc.buf.Len()-quotes, 1); limit <= 0 || room < limit {
+			limit = room
+		}
+	}
+	return limit
+}
+
+// dropOverflow drops the bytes written since start if the entry exceeds the
+// max entry size, or the entry was truncated already, and reports whether
+// they were dropped.
+func (enc *JsonEncoder) dropOverflow(start int) bool {
+	if limit := enc.limits.MaxEntrySize; limit > 0 && (enc.truncated > 0 || enc.buf.Len() > limit) {
+		enc.truncated += enc.buf.Len() - start
+		enc.buf.Truncate(start)
+		return true
+	}
+	return false
+}
+
+func appendTruncatedMarker(buf *Buffer, n int, unit string) {
+	buf.AppendString("…(truncated ")
+	buf.AppendInt(int64(n))
+	buf.AppendByte(' ')
+	buf.AppendString(unit)
+	buf.AppendByte(')')
+}
+
+// writeTruncatedMarker writes the marker as a string value.
+func (enc *JsonEncoder) writeTruncatedMarker(n int, unit string) {
+	enc.writeQuote()
+	enc.beginColor(enc.colors.attr.StringColor)
+	appendTruncatedMarker(enc.buf, n, unit)
+	enc.endColor()
+	enc.writeQuote()
+}
+
+// writeTruncatedKeys writes the marker of the object keys exceeding the limit.
+func (enc *JsonEncoder) writeTruncatedKeys(n int) {
+	enc.writeKey("…")
+	enc.writeTruncatedMarker(n, "keys")
+}
+
+func (enc *JsonEncoder) maxArrayElements(n int) int {
+	if limit := enc.limits.MaxArrayElements; limit > 0 && n > limit {
+		return limit
+	}
+	return n
+}
+
+func (enc *JsonEncoder) maxObjectKeys(n int) int {
+	if limit := enc.limits.MaxObjectKeys; limit > 0 && n > limit {
+		return limit
+	}
+	return n
+}
+
+// canNest reports whether an object or array can be opened at the current
+// depth, otherwise it writes the marker instead.
+func (enc *JsonEncoder) canNest() bool {
+	if limit := enc.limits.MaxDepth; limit > 0 && enc.depth > limit {
+		enc.writeQuotedString("…(max depth)")
+		return false
+	}
+	return true
+}
+
+// enterRef pushes the reference onto the stack of the values being encoded,
+// or writes the marker and reports false if it's already there.
+func (enc *JsonEncoder) enterRef(ref visitedRef) bool {
+	for i := range enc.visited {
+		if enc.visited[i] == ref {
+			enc.writeQuotedString("…(cycle)")
+			return false
+		}
+	}
+	enc.visited = append(enc.visited, ref)
+	return true
+}
+
+func (enc *JsonEncoder) leaveRef() { enc.visited = enc.visited[:len(enc.visited)-1] }
+
+func mapRef(v reflect.Value) visitedRef { return visitedRef{ptr: v.UnsafePointer(), len: -1} }
+
+func sliceRef[T any](v []T) visitedRef {
+	return visitedRef{ptr: unsafe.Pointer(unsafe.SliceData(v)), len: len(v)}
+}
@@ -14,13 +14,33 @@ type LogContext struct {
 	levelF       levelField
 	timeF        timeField
 	callerF      callerField
+	stackF       stacktraceField
 	enc          encoder
 	colors       colorfulset
 	writer       WriteSyncer
 	preFields    []Field
 	msgKey       string
+	templateKey  string
 	escapeQuote  bool
+	development  bool
 	reflectValue bool
+	sortMapKeys  bool
+	durationEnc  DurationEncoding
+	errorOpt     ErrorOption
+	redactor     *redactor
+	limits       LimitOption
+	lazyPrefix   bool
+	binary       BinaryOption
+	redactURL    bool
+	dupKeys      DuplicateKeyOption
+	sampler      *sampler
+	live         *liveConfig
+	liveGen      uint64
+	// the context the logger was built from before the config is applied, the
+	// snapshots are rebuilt from it on reload
+	liveBase *LogContext
+	// the changes of the derived loggers, which are replayed on reload
+	liveDerives []func(*LogContext)
 }
 
 func NewLogContext() *LogContext {
@@ -75,6 +95,13 @@ func (lc *LogContext) WithMsgKey(key string) *LogContext {
 	return lc
 }
 
+// WithTemplateKey sets the key of the templates written by Infot and the
+// like, default: "msg_template".
+func (lc *LogContext) WithTemplateKey(key string) *LogContext {
+	lc.templateKey = key
+	return lc
+}
+
 func (lc *LogContext) WithLevelKey(enable bool, option LevelOption) *LogContext {
 	lc.levelF.enable = enable
 	if enable {
@@ -100,6 +127,16 @@ func (lc *LogContext) WithTimeKey(enable bool, option TimeOption) *LogContext {
 	return lc
 }
 
+// WithFieldTime encodes the Time fields with option instead of the TimeOption
+// of the entry time.
+func (lc *LogContext) WithFieldTime(enable bool, option FieldTimeOption) *LogContext {
+	lc.timeF.fieldEnable = enable
+	if enable {
+		lc.timeF.fieldOption = option
+	}
+	return lc
+}
+
 func (lc *LogContext) WithCallerKey(enable bool, option CallerOption) *LogContext {
 	lc.callerF.enable = enable
 	if enable {
@@ -120,16 +157,114 @@ func (lc *LogContext) WithCallerKey(enable bool, option CallerOption) *LogContex
 	return lc
 }
 
+// WithStacktrace writes the stacktrace of the log call in the entries at or
+// above option.Level, the console encoder writes it on the following lines.
+func (lc *LogContext) WithStacktrace(enable bool, option StacktraceOption) *LogContext {
+	lc.stackF.enable = enable
+	if enable {
+		if len(option.StacktraceKey) == 0 {
+			option.StacktraceKey = "stacktrace"
+		}
+		lc.stackF.option = option
+	}
+	return lc
+}
+
 func (lc *LogContext) WithEscapeQuote(enable bool) *LogContext {
 	lc.escapeQuote = enable
 	return lc
 }
 
+// WithDevelopment makes DPanic, DPanicf and DPanicWith panic after the entry
+// is written, otherwise they behave like Error.
+func (lc *LogContext) WithDevelopment(enable bool) *LogContext {
+	lc.development = enable
+	return lc
+}
+
 func (lc *LogContext) WithReflectValue(enable bool) *LogContext {
 	lc.reflectValue = enable
 	return lc
 }
 
+// WithSampling samples the entries with the same level and message, the sampling
+// counters are shared by the loggers derived from lc.
+func (lc *LogContext) WithSampling(enable bool, option SamplingOption) *LogContext {
+	lc.sampler = nil
+	if enable {
+		lc.sampler = newSampler(option)
+	}
+	return lc
+}
+
+// WithSortedMapKeys writes the keys of maps in sorted order, so that the same
+// entries produce identical lines.
+func (lc *LogContext) WithSortedMapKeys(enable bool) *LogContext {
+	lc.sortMapKeys = enable
+	return lc
+}
+
+// WithDurationEncoding sets the encoding of the Duration fields and the durations
+// in arrays and Any values, default: DurationString.
+func (lc *LogContext) WithDurationEncoding(encoding DurationEncoding) *LogContext {
+	lc.durationEnc = encoding
+	return lc
+}
+
+// WithErrorDetails writes the details of the Error fields selected by option
+// as the sibling keys of the fields.
+func (lc *LogContext) WithErrorDetails(enable bool, option ErrorOption) *LogContext {
+	if !enable {
+		option = ErrorOption{}
+	}
+	lc.errorOpt = option
+	return lc
+}
+
+// WithRedaction replaces the values of the keys matching option.Keys at any
+// nesting depth and the matches of option.Patterns in the string values with
+// the replacement.
+func (lc *LogContext) WithRedaction(enable bool, option RedactOption) *LogContext {
+	if enable {
+		lc.redactor = newRedactor(option)
+	} else {
+		lc.redactor = nil
+	}
+	return lc
+}
+
+// WithLimits bounds the size of the entries, the maps and slices referencing
+// themselves are always written as "…(cycle)".
+func (lc *LogContext) WithLimits(enable bool, option LimitOption) *LogContext {
+	if !enable {
+		option = LimitOption{}
+	}
+	lc.limits = option
+	return lc
+}
+
+// WithBinary sets the encoding and the size cap of the Binary fields.
+func (lc *LogContext) WithBinary(option BinaryOption) *LogContext {
+	lc.binary = option
+	return lc
+}
+
+// WithURLRedaction redacts the userinfo and the query values of the URL fields.
+func (lc *LogContext) WithURLRedaction(enable bool) *LogContext {
+	lc.redactURL = enable
+	return lc
+}
+
+// WithDuplicateKey sets the policy for the top-level fields with the same key,
+// including the level, time, caller and msg keys, the preFields and the fields.
+func (lc *LogContext) WithDuplicateKey(option DuplicateKeyOption) *LogContext {
+	if len(option.Suffix) == 0 {
+		option.Suffix = "_"
+	}
+	lc.dupKeys = option
+	return lc
+}
+
 func (lc *LogContext) WithWriter(writer WriteSyncer) *LogContext {
 	lc.writer = writer
 	return lc
@@ -147,15 +282,78 @@ func (lc *LogContext) WithEncoder(encoder EncoderType) *LogContext {
 	return lc
 }
 
+// jsonEncoder returns the JsonEncoder of lc, which is wrapped by the console
+// encoder, or nil if lc has no encoder.
+func (lc *LogContext) jsonEncoder() *JsonEncoder {
+	switch enc := lc.enc.(type) {
+	case *JsonEncoder:
+		return enc
+	case *ConsoleEncoder:
+		return enc.jsonEncoder
+	}
+	return nil
+}
+
 func (lc *LogContext) WithLevel(level LevelType) *LogContext {
 	lc.levelT = level
 	return lc
 }
 
+// Build snapshots the configuration of lc into a new logger, the following
+// changes of lc don't affect the loggers built before, so lc can be reused as
+// a builder for other loggers. If lc is watched by a ConfigWatcher, the config
+// is applied over the snapshot of lc on each reload.
 func (lc *LogContext) Build() Logger {
-	lc.WithMsgKey(lc.msgKey)
-	if lc.enc != nil {
-		lc.enc.Init()
+	return lc.build()
+}
+
+// build returns the concrete logger, Build stays inlinable so that the calls
+// on the returned Logger can be devirtualized and their fields don't escape.
+func (lc *LogContext) build() *LoggerX {
+	var nlc *LogContext
+	if lc.live != nil {
+		base := lc.Copy()
+		base.liveBase, base.liveDerives = nil, nil
+		nlc = (&LogContext{live: lc.live, liveBase: base}).reload(lc.live.state.Load())
+	} else {
+		nlc = lc.Copy()
+		nlc.WithMsgKey(nlc.msgKey)
+		if nlc.enc != nil {
+			nlc.enc.Init()
+		}
+	}
+	l := new(LoggerX)
+	l.logCtx.Store(nlc)
+	return l
+}
+
+// reload returns a snapshot rebuilt from the context the logger was built from
+// with the latest config and the changes of the derived loggers applied, so
+// that the settings removed from the config fall back to the built context.
+func (lc *LogContext) reload(state *liveState) *LogContext {
+	nlc := lc.liveBase.Copy()
+	nlc.applyConfig(state.cfg, state.writer, state.sampler)
+	for _, derive := range lc.liveDerives {
+		derive(nlc)
+	}
+	nlc.WithMsgKey(nlc.msgKey)
+	nlc.live, nlc.liveGen = lc.live, state.gen
+	nlc.liveBase, nlc.liveDerives = lc.liveBase, lc.liveDerives
+	if nlc.enc != nil {
+		nlc.enc.Init()
+	}
+	return nlc
+}
+
+// derive returns a copy of lc changed by fn for a derived logger.
+func (lc *LogContext) derive(fn func(*LogContext)) *LogContext {
+	nlc := lc.Copy()
+	fn(nlc)
+	if nlc.live != nil {
+		nlc.liveDerives = append(lc.liveDerives[:len(lc.liveDerives):len(lc.liveDerives)], fn)
+	}
+	if nlc.enc != nil {
+		nlc.enc.Init()
 	}
-	return &LoggerX{logCtx: lc}
+	return nlc
 }
@@ -6,17 +6,42 @@ type Logger interface {
 	Info(msg string, fields ...Field)
 	Warn(msg string, fields ...Field)
 	Error(msg string, fields ...Field)
+	DPanic(msg string, fields ...Field)
 	Fatal(msg string, fields ...Field)
 	Panic(msg string, fields ...Field)
+	Log(level LevelType, msg string, fields ...Field)
 	Tracef(format string, args ...any)
 	Debugf(format string, args ...any)
 	Infof(format string, args ...any)
 	Warnf(format string, args ...any)
 	Errorf(format string, args ...any)
+	DPanicf(format string, args ...any)
 	Panicf(format string, args ...any)
 	Fatalf(format string, args ...any)
+	Logf(level LevelType, format string, args ...any)
+	Tracefw(format string, args []any, fields ...Field)
+	Debugfw(format string, args []any, fields ...Field)
+	Infofw(format string, args []any, fields ...Field)
+	Warnfw(format string, args []any, fields ...Field)
+	Errorfw(format string, args []any, fields ...Field)
+	DPanicfw(format string, args []any, fields ...Field)
+	Panicfw(format string, args []any, fields ...Field)
+	Fatalfw(format string, args []any, fields ...Field)
+	Tracet(template string, fields ...Field)
+	Debugt(template string, fields ...Field)
+	Infot(template string, fields ...Field)
+	Warnt(template string, fields ...Field)
+	Errort(template string, fields ...Field)
+	DPanict(template string, fields ...Field)
+	Panict(template string, fields ...Field)
+	Fatalt(template string, fields ...Field)
 	PanicWith(err error)
 	ErrorWith(err error)
+	DPanicWith(err error)
 	FatalWith(err error)
 	With(fields ...Field) Logger
+	WithLazy(fields ...Field) Logger
+	WithOptions(opts ...Option) Logger
+	Recover(msg string, opts ...RecoverOption)
+	Events() EventLogger
 }
@@ -1,10 +1,14 @@
 package logx
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -59,19 +63,76 @@ func Lock(ws WriteSyncer) WriteSyncer {
 	return &lockedWriteSyncer{ws: ws}
 }
 
+type multiWriteSyncer []WriteSyncer
+
+func (ws multiWriteSyncer) Write(p []byte) (int, error) {
+	var err error
+	nWritten := 0
+	for _, w := range ws {
+		n, werr := w.Write(p)
+		err = errors.Join(err, werr)
+		if nWritten == 0 && n != 0 {
+			nWritten = n
+		} else if n < nWritten {
+			nWritten = n
+		}
+	}
+	return nWritten, err
+}
+
+func (ws multiWriteSyncer) Sync() error {
+	var err error
+	for _, w := range ws {
+		err = errors.Join(err, w.Sync())
+	}
+	return err
+}
+
+// MultiWriteSyncer creates a WriteSyncer that duplicates its writes and
+// sync calls, much like io.MultiWriter.
+func MultiWriteSyncer(ws ...WriteSyncer) WriteSyncer {
+	if len(ws) == 1 {
+		return ws[0]
+	}
+	return multiWriteSyncer(slices.Clone(ws))
+}
+
 type LoggerX struct {
-	logCtx *LogContext
+	logCtx atomic.Pointer[LogContext]
+}
+
+// context returns the current snapshot of the logger, which is swapped with a
+// reconfigured one when the watched config file has changed.
+func (l *LoggerX) context() *LogContext {
+	lc := l.logCtx.Load()
+	if lc.live == nil {
+		return lc
+	}
+	state := lc.live.state.Load()
+	if state.gen == lc.liveGen {
+		return lc
+	}
+	if l.logCtx.CompareAndSwap(lc, lc.reload(state)) {
+		return l.logCtx.Load()
+	}
+	// raced with another goroutine
+	return l.context()
 }
 
 func (l *LoggerX) print(level LevelType, msg string, fields []Field) {
+	lc := l.context()
 	// discard the log
-	if l.logCtx.writer == nil || l.logCtx.writer == io.Discard {
+	if lc.writer == nil || lc.writer == io.Discard {
+		return
+	}
+	if lc.levelT > level {
 		return
 	}
-	if l.skipLevelLog(level) {
+	now := time.Now()
+	if lc.sampler != nil && !lc.sampler.check(level, msg, now) {
 		return
 	}
-	l.output(level, msg, fields)
+	l.output(lc, entry{level: level, message: msg, time: now}, fields)
 }
 
 func (l *LoggerX) Trace(msg string, fields ...Field) { l.print(LevelTrace, msg, fields) }
@@ -84,6 +145,14 @@ func (l *LoggerX) Warn(msg string, fields ...Field) { l.print(LevelWarn, msg, fi
 
 func (l *LoggerX) Error(msg string, fields ...Field) { l.print(LevelError, msg, fields) }
 
+// DPanic panics after the entry is written in the development mode.
+func (l *LoggerX) DPanic(msg string, fields ...Field) {
+	l.print(LevelDPanic, msg, fields)
+	if l.context().development {
+		panic(msg)
+	}
+}
+
 func (l *LoggerX) Fatal(msg string, fields ...Field) {
 	l.print(LevelFatal, msg, fields)
 	os.Exit(1)
@@ -94,35 +163,156 @@ func (l *LoggerX) Panic(msg string, fields ...Field) {
 	panic(msg)
 }
 
+// Log writes the entry at the level, the entries of LevelDPanic, LevelPanic
+// and LevelFatal panic or call os.Exit(1) like DPanic, Panic and Fatal.
+func (l *LoggerX) Log(level LevelType, msg string, fields ...Field) {
+	l.print(level, msg, fields)
+	switch level {
+	case LevelDPanic:
+		if l.context().development {
+			panic(msg)
+		}
+	case LevelPanic:
+		panic(msg)
+	case LevelFatal:
+		os.Exit(1)
+	}
+}
+
 func (l *LoggerX) Tracef(format string, args ...any) {
-	l.print(LevelTrace, fmt.Sprintf(format, args...), nil)
+	msg, fields := sprintf(format, args, nil)
+	l.print(LevelTrace, msg, fields)
 }
 
 func (l *LoggerX) Debugf(format string, args ...any) {
-	l.print(LevelDebug, fmt.Sprintf(format, args...), nil)
+	msg, fields := sprintf(format, args, nil)
+	l.print(LevelDebug, msg, fields)
 }
 
 func (l *LoggerX) Infof(format string, args ...any) {
-	l.print(LevelInfo, fmt.Sprintf(format, args...), nil)
+	msg, fields := sprintf(format, args, nil)
+	l.print(LevelInfo, msg, fields)
 }
 
 func (l *LoggerX) Warnf(format string, args ...any) {
-	l.print(LevelWarn, fmt.Sprintf(format, args...), nil)
+	msg, fields := sprintf(format, args, nil)
+	l.print(LevelWarn, msg, fields)
 }
 
 func (l *LoggerX) Errorf(format string, args ...any) {
-	l.print(LevelError, fmt.Sprintf(format, args...), nil)
+	msg, fields := sprintf(format, args, nil)
+	l.print(LevelError, msg, fields)
+}
+
+func (l *LoggerX) DPanicf(format string, args ...any) {
+	msg, fields := sprintf(format, args, nil)
+	l.print(LevelDPanic, msg, fields)
+	if l.context().development {
+		panic(msg)
+	}
 }
 
 func (l *LoggerX) Fatalf(format string, args ...any) {
-	l.print(LevelFatal, fmt.Sprintf(format, args...), nil)
+	msg, fields := sprintf(format, args, nil)
+	l.print(LevelFatal, msg, fields)
 	os.Exit(1)
 }
 
 func (l *LoggerX) Panicf(format string, args ...any) {
-	value := fmt.Sprintf(format, args...)
-	l.print(LevelPanic, value, nil)
-	panic(value)
+	msg, fields := sprintf(format, args, nil)
+	l.print(LevelPanic, msg, fields)
+	panic(msg)
+}
+
+// Logf is the formatted version of Log.
+func (l *LoggerX) Logf(level LevelType, format string, args ...any) {
+	msg, fields := sprintf(format, args, nil)
+	l.print(level, msg, fields)
+	switch level {
+	case LevelDPanic:
+		if l.context().development {
+			panic(msg)
+		}
+	case LevelPanic:
+		panic(msg)
+	case LevelFatal:
+		os.Exit(1)
+	}
+}
+
+// Tracefw is like Tracef with the fields.
+func (l *LoggerX) Tracefw(format string, args []any, fields ...Field) {
+	msg, fields := sprintf(format, args, fields)
+	l.print(LevelTrace, msg, fields)
+}
+
+// Debugfw is like Debugf with the fields.
+func (l *LoggerX) Debugfw(format string, args []any, fields ...Field) {
+	msg, fields := sprintf(format, args, fields)
+	l.print(LevelDebug, msg, fields)
+}
+
+// Infofw is like Infof with the fields.
+func (l *LoggerX) Infofw(format string, args []any, fields ...Field) {
+	msg, fields := sprintf(format, args, fields)
+	l.print(LevelInfo, msg, fields)
+}
+
+// Warnfw is like Warnf with the fields.
+func (l *LoggerX) Warnfw(format string, args []any, fields ...Field) {
+	msg, fields := sprintf(format, args, fields)
+	l.print(LevelWarn, msg, fields)
+}
+
+// Errorfw is like Errorf with the fields.
+func (l *LoggerX) Errorfw(format string, args []any, fields ...Field) {
+	msg, fields := sprintf(format, args, fields)
+	l.print(LevelError, msg, fields)
+}
+
+// DPanicfw is like DPanicf with the fields.
+func (l *LoggerX) DPanicfw(format string, args []any, fields ...Field) {
+	msg, fields := sprintf(format, args, fields)
+	l.print(LevelDPanic, msg, fields)
+	if l.context().development {
+		panic(msg)
+	}
+}
+
+// Fatalfw is like Fatalf with the fields.
+func (l *LoggerX) Fatalfw(format string, args []any, fields ...Field) {
+	msg, fields := sprintf(format, args, fields)
+	l.print(LevelFatal, msg, fields)
+	os.Exit(1)
+}
+
+// Panicfw is like Panicf with the fields.
+func (l *LoggerX) Panicfw(format string, args []any, fields ...Field) {
+	msg, fields := sprintf(format, args, fields)
+	l.print(LevelPanic, msg, fields)
+	panic(msg)
+}
+
+// sprintf formats the msg of the formatted log methods. If the format wraps
+// errors with %w, the wrapped errors are appended to fields as an Error field
+// with the key "error", the multiple errors are joined by errors.Join.
+func sprintf(format string, args []any, fields []Field) (string, []Field) {
+	if !strings.Contains(format, "%w") {
+		return fmt.Sprintf(format, args...), fields
+	}
+	err := fmt.Errorf(format, args...)
+	var wrapped error
+	switch err := err.(type) {
+	case interface{ Unwrap() error }:
+		wrapped = err.Unwrap()
+	case interface{ Unwrap() []error }:
+		wrapped = errors.Join(err.Unwrap()...)
+	}
+	if wrapped != nil {
+		// don't append to the caller's slice
+		fields = append(fields[:len(fields):len(fields)], Error("error", wrapped))
+	}
+	return err.Error(), fields
 }
 
 func (l *LoggerX) ErrorWith(err error) {
@@ -133,6 +323,18 @@ func (l *LoggerX) ErrorWith(err error) {
 	l.print(LevelError, value, nil)
 }
 
+// DPanicWith is like PanicWith, the nil err is ignored, and panics with the err
+// only in the development mode.
+func (l *LoggerX) DPanicWith(err error) {
+	if err == nil {
+		return
+	}
+	l.print(LevelDPanic, err.Error(), nil)
+	if l.context().development {
+		panic(err)
+	}
+}
+
 func (l *LoggerX) PanicWith(err error) {
 	if err == nil {
 		return
@@ -149,47 +351,73 @@ func (l *LoggerX) FatalWith(err error) {
 	os.Exit(1)
 }
 
-func (l *LoggerX) skipLevelLog(expect LevelType) bool {
-	return l.logCtx.levelT > expect
-}
-
-func (l *LoggerX) clone() *LoggerX {
-	clone := &LoggerX{logCtx: l.logCtx.Copy()}
-	if clone.logCtx.enc != nil {
-		clone.logCtx.enc.Init()
-	}
+func (l *LoggerX) clone(lazy bool, fields ...Field) *LoggerX {
+	clone := new(LoggerX)
+	lc := l.context().derive(func(lc *LogContext) {
+		lc.WithFields(fields...)
+		if lazy {
+			lc.lazyPrefix = true
+		}
+	})
+	clone.logCtx.Store(lc)
 	return clone
 }
 
 func (l *LoggerX) With(fields ...Field) Logger {
-	nl := l.clone()
-	nl.logCtx = nl.logCtx.WithFields(fields...)
-	return nl
+	return l.clone(false, fields...)
+}
+
+// WithLazy is like With but the context fields are encoded on first use, so
+// that the Lazy fields are not called if the logger never writes an entry.
+func (l *LoggerX) WithLazy(fields ...Field) Logger {
+	return l.clone(true, fields...)
 }
 
-func (l *LoggerX) output(level LevelType, msg string, fields []Field) {
-	if l.logCtx.enc == nil {
+// WithOptions returns a logger derived from l with the options applied.
+func (l *LoggerX) WithOptions(opts ...Option) Logger {
+	clone := new(LoggerX)
+	lc := l.context().derive(func(lc *LogContext) {
+		for _, opt := range opts {
+			opt(lc)
+		}
+	})
+	clone.logCtx.Store(lc)
+	return clone
+}
+
+func (l *LoggerX) output(lc *LogContext, ent entry, fields []Field) {
+	if lc.enc == nil {
 		return
 	}
 
-	ent := entry{
-		level:   level,
-		message: msg,
-		time:    time.Now(),
+	if ent.stack == nil && lc.stackF.enable && ent.level >= lc.stackF.option.Level {
+		// skip runtime.Callers, output, print and the log method
+		ent.stack = captureStacktrace(4 + lc.callerF.option.CallerSkip)
+	}
+	if ent.stack != nil {
+		defer ent.stack.free()
 	}
 
 	var buf *Buffer
 	var err error
-	if buf, err = l.logCtx.enc.Encode(ent, fields); err != nil {
+	// call the encoders directly, the fields passed through the interface
+	// escape to the heap
+	switch enc := lc.enc.(type) {
+	case *JsonEncoder:
+		buf, err = enc.Encode(ent, fields)
+	case *ConsoleEncoder:
+		buf, err = enc.Encode(ent, fields)
+	}
+	if err != nil {
 		return
 	}
 	if buf.Len() > 0 && buf.Bytes()[buf.Len()-1] != '\n' {
 		buf.AppendByte('\n')
 	}
-	l.logCtx.writer.Write(buf.Bytes())
+	lc.writer.Write(buf.Bytes())
 	bufPool.Put(buf)
 
-	if l.logCtx.levelT > LevelError {
-		_ = l.logCtx.writer.Sync()
+	if lc.levelT > LevelError {
+		_ = lc.writer.Sync()
 	}
 }
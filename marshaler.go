@@ -0,0 +1,64 @@
+package logx
+
+import "time"
+
+// ObjectMarshaler allows user-defined types to efficiently add themselves to the
+// log entry as an object, instead of being formatted by "%v" or reflection.
+type ObjectMarshaler interface {
+	MarshalLogObject(ObjectEncoder) error
+}
+
+// ArrayMarshaler allows user-defined types to efficiently add themselves to the
+// log entry as an array.
+type ArrayMarshaler interface {
+	MarshalLogArray(ArrayEncoder) error
+}
+
+// ObjectMarshalerFunc is a type adapter that turns a function into an ObjectMarshaler.
+type ObjectMarshalerFunc func(ObjectEncoder) error
+
+func (f ObjectMarshalerFunc) MarshalLogObject(enc ObjectEncoder) error { return f(enc) }
+
+// ArrayMarshalerFunc is a type adapter that turns a function into an ArrayMarshaler.
+type ArrayMarshalerFunc func(ArrayEncoder) error
+
+func (f ArrayMarshalerFunc) MarshalLogArray(enc ArrayEncoder) error { return f(enc) }
+
+// ObjectEncoder is implemented by the encoders to add the key-values of an
+// ObjectMarshaler to the object being encoded.
+type ObjectEncoder interface {
+	AddString(key, value string)
+	AddBool(key string, value bool)
+	AddInt(key string, value int)
+	AddInt64(key string, value int64)
+	AddUint64(key string, value uint64)
+	AddFloat64(key string, value float64)
+	AddTime(key string, value time.Time)
+	AddDuration(key string, value time.Duration)
+	AddError(key string, value error)
+	// AddAny uses the same type formatters as the Any field.
+	AddAny(key string, value any)
+	AddField(field Field)
+	AddObject(key string, value ObjectMarshaler) error
+	AddArray(key string, value ArrayMarshaler) error
+	// OpenNamespace nests the following key-values under the key until the
+	// object ends.
+	OpenNamespace(key string)
+}
+
+// ArrayEncoder is implemented by the encoders to append the elements of an
+// ArrayMarshaler to the array being encoded.
+type ArrayEncoder interface {
+	AppendString(value string)
+	AppendBool(value bool)
+	AppendInt(value int)
+	AppendInt64(value int64)
+	AppendUint64(value uint64)
+	AppendFloat64(value float64)
+	AppendTime(value time.Time)
+	AppendDuration(value time.Duration)
+	AppendError(value error)
+	AppendAny(value any)
+	AppendObject(value ObjectMarshaler) error
+	AppendArray(value ArrayMarshaler) error
+}
@@ -0,0 +1,332 @@
+package logx
+
+import (
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+	"unsafe"
+)
+
+// IP encodes the canonical string of ip, the IPv4-mapped IPv6 addresses are
+// written in the IPv4 form like net.IP.String. The ip is not copied.
+func IP(key string, ip net.IP) Field {
+	return Field{Key: key, Type: IPType, StringValue: unsafe.String(unsafe.SliceData(ip), len(ip))}
+}
+
+func Addr(key string, addr netip.Addr) Field {
+	return Field{Key: key, Type: AddrType, AnyValue: addr}
+}
+
+// Addrs encodes the addresses as an array of the canonical strings, the slice
+// is not copied.
+func Addrs(key string, addrs []netip.Addr) Field {
+	return sliceField(key, AddrsType, addrs)
+}
+
+func AddrPort(key string, addrPort netip.AddrPort) Field {
+	return Field{Key: key, Type: AddrPortType, AnyValue: addrPort}
+}
+
+func Prefix(key string, prefix netip.Prefix) Field {
+	return Field{Key: key, Type: PrefixType, AnyValue: prefix}
+}
+
+// HardwareAddr encodes the MAC address like net.HardwareAddr.String, the addr
+// is not copied.
+func HardwareAddr(key string, addr net.HardwareAddr) Field {
+	return Field{Key: key, Type: HardwareAddrType, StringValue: unsafe.String(unsafe.SliceData(addr), len(addr))}
+}
+
+// URL encodes the URL like url.URL.String, the userinfo and the query values
+// are redacted if WithURLRedaction is enabled.
+func URL(key string, u *url.URL) Field {
+	return Field{Key: key, Type: URLType, AnyValue: u}
+}
+
+func (enc *JsonEncoder) beginStringValue() {
+	enc.writeQuote()
+	enc.beginColor(enc.colors.attr.StringColor)
+}
+
+func (enc *JsonEncoder) endStringValue() {
+	enc.endColor()
+	enc.writeQuote()
+}
+
+func (enc *JsonEncoder) writeFieldIP(ip net.IP) {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		enc.writeFieldNil()
+		return
+	}
+	enc.writeFieldAddr(addr.Unmap())
+}
+
+func (enc *JsonEncoder) writeFieldAddr(addr netip.Addr) {
+	if !addr.IsValid() {
+		enc.writeFieldNil()
+		return
+	}
+	enc.beginStringValue()
+	enc.buf.bs = addr.AppendTo(enc.buf.bs)
+	enc.endStringValue()
+}
+
+func (enc *JsonEncoder) writeFieldAddrPort(addrPort netip.AddrPort) {
+	if !addrPort.IsValid() {
+		enc.writeFieldNil()
+		return
+	}
+	enc.beginStringValue()
+	enc.buf.bs = addrPort.AppendTo(enc.buf.bs)
+	enc.endStringValue()
+}
+
+func (enc *JsonEncoder) writeFieldPrefix(prefix netip.Prefix) {
+	if !prefix.IsValid() {
+		enc.writeFieldNil()
+		return
+	}
+	enc.beginStringValue()
+	enc.buf.bs = prefix.AppendTo(enc.buf.bs)
+	enc.endStringValue()
+}
+
+func (enc *JsonEncoder) writeFieldHardwareAddr(addr net.HardwareAddr) {
+	const hexDigit = "0123456789abcdef"
+	enc.beginStringValue()
+	for i, b := range addr {
+		if i > 0 {
+			enc.buf.AppendByte(':')
+		}
+		enc.buf.AppendByte(hexDigit[b>>4])
+		enc.buf.AppendByte(hexDigit[b&0xF])
+	}
+	enc.endStringValue()
+}
+
+// writeFieldURL writes the URL in the same way as url.URL.String, the escaped
+// parts are appended to the buffer directly instead of being allocated.
+func (enc *JsonEncoder) writeFieldURL(u *url.URL) {
+	if u == nil {
+		enc.writeFieldNil()
+		return
+	}
+	enc.beginStringValue()
+	start := enc.buf.Len()
+	if u.Scheme != "" {
+		enc.writeRawString(u.Scheme)
+		enc.buf.AppendByte(':')
+	}
+	if u.Opaque != "" {
+		enc.writeRawString(u.Opaque)
+	} else {
+		if u.Scheme != "" || u.Host != "" || u.User != nil {
+			if !u.OmitHost || u.Host != "" || u.User != nil {
+				if u.Host != "" || u.Path != "" || u.User != nil {
+					enc.buf.AppendString("//")
+				}
+				if u.User != nil {
+					if enc.redactURL {
+						enc.writeRawString(redactedValue)
+					} else {
+						appendEscapedURL(enc.buf, u.User.Username(), encodeUserPassword)
+						if password, ok := u.User.Password(); ok {
+							enc.buf.AppendByte(':')
+							appendEscapedURL(enc.buf, password, encodeUserPassword)
+						}
+					}
+					enc.buf.AppendByte('@')
+				}
+				enc.writeEscapedHost(u.Host)
+			}
+		}
+		// the valid RawPath is written as is like url.URL.EscapedPath
+		path, escaped := u.Path, u.Path == "*"
+		if u.RawPath != "" && validEncodedURL(u.RawPath, encodePath) && unescapesTo(u.RawPath, u.Path) {
+			path, escaped = u.RawPath, true
+		}
+		if path != "" && path[0] != '/' && u.Host != "" {
+			enc.buf.AppendByte('/')
+		}
+		if enc.buf.Len() == start {
+			// a colon in the first segment of a relative path would be
+			// mistaken for a scheme, the colons are not escaped in the path
+			if segment, _, _ := strings.Cut(path, "/"); strings.Contains(segment, ":") {
+				enc.buf.AppendString("./")
+			}
+		}
+		if escaped {
+			enc.buf.AppendString(path)
+		} else {
+			appendEscapedURL(enc.buf, path, encodePath)
+		}
+	}
+	if u.ForceQuery || u.RawQuery != "" {
+		enc.buf.AppendByte('?')
+		if enc.redactURL {
+			enc.writeRedactedQuery(u.RawQuery)
+		} else {
+			enc.writeRawString(u.RawQuery)
+		}
+	}
+	if u.Fragment != "" {
+		enc.buf.AppendByte('#')
+		if u.RawFragment != "" && validEncodedURL(u.RawFragment, encodeFragment) && unescapesTo(u.RawFragment, u.Fragment) {
+			enc.buf.AppendString(u.RawFragment)
+		} else {
+			appendEscapedURL(enc.buf, u.Fragment, encodeFragment)
+		}
+	}
+	enc.endStringValue()
+}
+
+// writeEscapedHost writes the host percent-encoded like url.URL.String, the
+// quotes kept by the host encoding are written like writeRawString.
+func (enc *JsonEncoder) writeEscapedHost(host string) {
+	for {
+		i := strings.IndexByte(host, '"')
+		if i < 0 {
+			appendEscapedURL(enc.buf, host, encodeHost)
+			return
+		}
+		appendEscapedURL(enc.buf, host[:i], encodeHost)
+		enc.writeRawString(host[i : i+1])
+		host = host[i+1:]
+	}
+}
+
+// writeRedactedQuery keeps the keys of the query and redacts the values.
+func (enc *JsonEncoder) writeRedactedQuery(query string) {
+	for {
+		pair, rest, more := strings.Cut(query, "&")
+		key, _, hasValue := strings.Cut(pair, "=")
+		enc.writeRawString(key)
+		if hasValue {
+			enc.buf.AppendByte('=')
+			enc.writeRawString(redactedValue)
+		}
+		if !more {
+			return
+		}
+		enc.buf.AppendByte('&')
+		query = rest
+	}
+}
+
+func (enc *JsonEncoder) writeFieldAddrs(addrs []netip.Addr) {
+	writeFieldArrayListFor(enc, addrs, (*JsonEncoder).writeFieldAddr)
+}
+
+// urlEncoding is the part of the URL to escape.
+// See net/url
+type urlEncoding uint8
+
+const (
+	encodePath urlEncoding = iota
+	encodeHost
+	encodeUserPassword
+	encodeFragment
+)
+
+// shouldEscapeURL reports whether the byte of the part must be percent-encoded.
+// See net/url
+func shouldEscapeURL(c byte, mode urlEncoding) bool {
+	if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' {
+		return false
+	}
+	if mode == encodeHost {
+		// the sub-delims, the port, the IPv6 literals and the characters which
+		// can't be percent-encoded in the hosts, the zones are encoded alike
+		switch c {
+		case '!', '$', '&', '\'', '(', ')', '*', '+', ',', ';', '=', ':', '[', ']', '<', '>', '"':
+			return false
+		}
+	}
+	switch c {
+	case '-', '_', '.', '~':
+		return false
+	case '$', '&', '+', ',', '/', ':', ';', '=', '?', '@':
+		switch mode {
+		case encodePath:
+			return c == '?'
+		case encodeUserPassword:
+			return c == '@' || c == '/' || c == '?' || c == ':'
+		case encodeFragment:
+			return false
+		}
+	}
+	if mode == encodeFragment {
+		switch c {
+		case '!', '(', ')', '*':
+			return false
+		}
+	}
+	return true
+}
+
+// appendEscapedURL appends the part percent-encoded like url.URL.String, the
+// result never needs the JSON escapes except the quotes of the hosts.
+func appendEscapedURL(buf *Buffer, s string, mode urlEncoding) {
+	const upperhex = "0123456789ABCDEF"
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; shouldEscapeURL(c, mode) {
+			buf.AppendByte('%')
+			buf.AppendByte(upperhex[c>>4])
+			buf.AppendByte(upperhex[c&15])
+		} else {
+			buf.AppendByte(c)
+		}
+	}
+}
+
+// validEncodedURL reports whether the raw part needs no more escaping.
+// See net/url
+func validEncodedURL(s string, mode urlEncoding) bool {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '!', '$', '&', '\'', '(', ')', '*', '+', ',', ';', '=', ':', '@', '[', ']', '%':
+		default:
+			if shouldEscapeURL(s[i], mode) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// unescapesTo reports whether the percent-encoded raw part is decoded to s
+// without allocating the decoded string.
+func unescapesTo(raw, s string) bool {
+	j := 0
+	for i := 0; i < len(raw); i, j = i+1, j+1 {
+		c := raw[i]
+		if c == '%' {
+			if i+2 >= len(raw) || !isHex(raw[i+1]) || !isHex(raw[i+2]) {
+				return false
+			}
+			c = unhex(raw[i+1])<<4 | unhex(raw[i+2])
+			i += 2
+		}
+		if j >= len(s) || s[j] != c {
+			return false
+		}
+	}
+	return j == len(s)
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
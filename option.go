@@ -0,0 +1,39 @@
+package logx
+
+// Option changes the configuration of the logger derived by WithOptions.
+type Option func(lc *LogContext)
+
+// AddCallerSkip increases the number of callers skipped by the caller and the
+// stacktrace, which is used by the wrappers of the logger.
+func AddCallerSkip(skip int) Option {
+	return func(lc *LogContext) {
+		lc.callerF.option.CallerSkip += skip
+	}
+}
+
+// AddStacktrace writes the stacktrace of the entries at or above the level.
+func AddStacktrace(level LevelType) Option {
+	return func(lc *LogContext) {
+		lc.WithStacktrace(true, StacktraceOption{
+			StacktraceKey: lc.stackF.option.StacktraceKey,
+			Level:         level,
+		})
+	}
+}
+
+// IncreaseLevel raises the level of the logger, the level lower than the
+// current one is ignored.
+func IncreaseLevel(level LevelType) Option {
+	return func(lc *LogContext) {
+		if level > lc.levelT {
+			lc.levelT = level
+		}
+	}
+}
+
+// WithCaller enables or disables the caller with the current CallerOption.
+func WithCaller(enable bool) Option {
+	return func(lc *LogContext) {
+		lc.WithCallerKey(enable, lc.callerF.option)
+	}
+}
@@ -0,0 +1,7 @@
+//go:build race
+
+package logx
+
+// sync.Pool drops the items randomly with the race detector, so that the
+// allocation checks are skipped.
+const raceEnabled = true
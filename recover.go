@@ -0,0 +1,97 @@
+package logx
+
+import (
+	"io"
+	"os"
+	"time"
+)
+
+type recoverAction uint8
+
+const (
+	recoverSwallow recoverAction = iota
+	recoverRepanic
+	recoverExit
+)
+
+type recoverOptions struct {
+	level    LevelType
+	fields   []Field
+	action   recoverAction
+	exitCode int
+}
+
+// RecoverOption configures Recover and Go.
+type RecoverOption func(*recoverOptions)
+
+// RecoverLevel sets the level of the entry of the panic, default: LevelError.
+func RecoverLevel(level LevelType) RecoverOption {
+	return func(o *recoverOptions) { o.level = level }
+}
+
+// RecoverFields adds the fields to the entry of the panic.
+func RecoverFields(fields ...Field) RecoverOption {
+	return func(o *recoverOptions) { o.fields = append(o.fields, fields...) }
+}
+
+// Repanic panics again with the recovered value after the entry is written.
+func Repanic() RecoverOption {
+	return func(o *recoverOptions) { o.action = recoverRepanic }
+}
+
+// ExitOnPanic calls os.Exit with the code after the entry is written.
+func ExitOnPanic(code int) RecoverOption {
+	return func(o *recoverOptions) {
+		o.action = recoverExit
+		o.exitCode = code
+	}
+}
+
+// Recover recovers the panic and writes an entry with the msg, the panic value
+// under the key "panic" and the stacktrace of the panicking goroutine starting
+// at the frame which panicked, then syncs the writer. The panic is swallowed
+// unless Repanic or ExitOnPanic is given. It must be called directly by defer:
+//
+//	defer logger.Recover("worker panicked")
+func (l *LoggerX) Recover(msg string, opts ...RecoverOption) {
+	value := recover()
+	if value == nil {
+		return
+	}
+	options := recoverOptions{level: LevelError}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	l.printRecovered(msg, value, &options)
+	switch options.action {
+	case recoverRepanic:
+		panic(value)
+	case recoverExit:
+		os.Exit(options.exitCode)
+	}
+}
+
+// printRecovered is the print of the recovered panics, which are not sampled.
+func (l *LoggerX) printRecovered(msg string, value any, options *recoverOptions) {
+	lc := l.context()
+	// discard the log
+	if lc.writer == nil || lc.writer == io.Discard {
+		return
+	}
+	if lc.levelT > options.level {
+		return
+	}
+	stack := captureStacktrace(0)
+	stack.panicked = true
+	fields := append(options.fields[:len(options.fields):len(options.fields)], Any("panic", value))
+	l.output(lc, entry{level: options.level, message: msg, time: time.Now(), stack: stack}, fields)
+	_ = lc.writer.Sync()
+}
+
+// Go runs fn in a new goroutine guarded by logger.Recover with the opts.
+func Go(logger Logger, fn func(), opts ...RecoverOption) {
+	go func() {
+		defer logger.Recover("goroutine panicked", opts...)
+		fn()
+	}()
+}
@@ -0,0 +1,94 @@
+package logx
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// The common patterns of the sensitive values for RedactOption.Patterns.
+var (
+	RedactEmail       = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	RedactCardNumber  = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+	RedactBearerToken = regexp.MustCompile(`(?i)bearer\s+[A-Za-z0-9._~+/=-]+`)
+)
+
+const redactedValue = "[REDACTED]"
+
+type RedactOption struct {
+	// the keys or glob patterns of path.Match of the values to redact at any
+	// nesting depth, the keys are matched case-insensitively
+	Keys []string
+	// the patterns to replace in the string values and the messages
+	Patterns []*regexp.Regexp
+	// the replacement of the redacted values, default: [REDACTED]
+	Replacement string
+}
+
+type redactor struct {
+	keys        []string
+	globs       []string
+	patterns    []*regexp.Regexp
+	replacement string
+}
+
+func newRedactor(option RedactOption) *redactor {
+	r := &redactor{patterns: option.Patterns, replacement: option.Replacement}
+	if len(r.replacement) == 0 {
+		r.replacement = redactedValue
+	}
+	for _, key := range option.Keys {
+		key = strings.ToLower(key)
+		if strings.ContainsAny(key, `*?[\`) {
+			r.globs = append(r.globs, key)
+		} else {
+			r.keys = append(r.keys, key)
+		}
+	}
+	return r
+}
+
+func (r *redactor) matchKey(key string) bool {
+	for _, k := range r.keys {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	if len(r.globs) == 0 {
+		return false
+	}
+	key = strings.ToLower(key)
+	for _, glob := range r.globs {
+		if ok, _ := path.Match(glob, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// replace replaces the matches of the patterns in value.
+func (r *redactor) replace(value string) string {
+	for _, pattern := range r.patterns {
+		if pattern.MatchString(value) {
+			value = pattern.ReplaceAllLiteralString(value, r.replacement)
+		}
+	}
+	return value
+}
+
+// addKey writes the key of an object member like writeKey, but writes the
+// replacement as the value if the key is redacted, in which case it returns
+// false and the value must be skipped. The key dropped by the duplicate key
+// policy is not written either.
+func (enc *JsonEncoder) addKey(key string) bool {
+	key, ok := enc.filterKey(key)
+	if !ok {
+		return false
+	}
+	enc.writeKey(key)
+	if enc.redactor != nil && enc.redactor.matchKey(key) {
+		enc.writeQuotedString(enc.redactor.replacement)
+		return false
+	}
+	return true
+}
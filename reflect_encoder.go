@@ -0,0 +1,604 @@
+package logx
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// reflectEncoderFunc encodes a value of a specific type, the encoders are
+// built once per type and cached in reflectEncoderCache.
+type reflectEncoderFunc func(enc *JsonEncoder, v reflect.Value)
+
+var reflectEncoderCache sync.Map // map[reflect.Type]reflectEncoderFunc
+
+var (
+	timeType            = reflect.TypeFor[time.Time]()
+	durationType        = reflect.TypeFor[time.Duration]()
+	fieldType           = reflect.TypeFor[Field]()
+	objectMarshalerType = reflect.TypeFor[ObjectMarshaler]()
+	arrayMarshalerType  = reflect.TypeFor[ArrayMarshaler]()
+	jsonMarshalerType   = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType   = reflect.TypeFor[encoding.TextMarshaler]()
+	errorType           = reflect.TypeFor[error]()
+	stringerType        = reflect.TypeFor[fmt.Stringer]()
+)
+
+// writeReflectValue encodes structs field by field honoring the json tags,
+// follows pointers and interfaces, and uses ObjectMarshaler, ArrayMarshaler,
+// json.Marshaler, encoding.TextMarshaler, error and fmt.Stringer in order.
+func (enc *JsonEncoder) writeReflectValue(v reflect.Value) {
+	if !v.IsValid() {
+		enc.writeFieldNil()
+		return
+	}
+	typeEncoder(v.Type())(enc, v)
+}
+
+func typeEncoder(t reflect.Type) reflectEncoderFunc {
+	if fi, ok := reflectEncoderCache.Load(t); ok {
+		return fi.(reflectEncoderFunc)
+	}
+
+	// To deal with recursive types, populate the map with an indirect func
+	// before we build it. This type waits on the real func (f) to be ready
+	// and then calls it. This indirect func is only used for recursive types.
+	// See encoding/json
+	var (
+		wg sync.WaitGroup
+		f  reflectEncoderFunc
+	)
+	wg.Add(1)
+	fi, loaded := reflectEncoderCache.LoadOrStore(t, reflectEncoderFunc(func(enc *JsonEncoder, v reflect.Value) {
+		wg.Wait()
+		f(enc, v)
+	}))
+	if loaded {
+		return fi.(reflectEncoderFunc)
+	}
+
+	f = newTypeEncoder(t, true)
+	wg.Done()
+	reflectEncoderCache.Store(t, f)
+	return f
+}
+
+func newTypeEncoder(t reflect.Type, allowAddr bool) reflectEncoderFunc {
+	switch t {
+	case timeType:
+		return func(enc *JsonEncoder, v reflect.Value) { enc.writeFieldTime(v.Interface().(time.Time)) }
+	case durationType:
+		return func(enc *JsonEncoder, v reflect.Value) { enc.writeFieldDuration(time.Duration(v.Int())) }
+	case fieldType:
+		return func(enc *JsonEncoder, v reflect.Value) { enc.writeFieldSingleObject(v.Interface().(Field)) }
+	}
+	if t.Kind() == reflect.Interface {
+		return interfaceEncoder
+	}
+	// the methods with pointer receivers can be used by addressable values
+	if allowAddr && t.Kind() != reflect.Pointer && marshalerEncoder(reflect.PointerTo(t)) != nil {
+		return condAddrEncoder(marshalerEncoder(reflect.PointerTo(t)), newTypeEncoder(t, false))
+	}
+	if f := marshalerEncoder(t); f != nil {
+		return f
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return func(enc *JsonEncoder, v reflect.Value) { enc.writeFieldBool(v.Bool()) }
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(enc *JsonEncoder, v reflect.Value) { enc.writeFieldInt64(v.Int()) }
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return func(enc *JsonEncoder, v reflect.Value) { enc.writeFieldUint64(v.Uint()) }
+	case reflect.Float32:
+		return func(enc *JsonEncoder, v reflect.Value) { enc.writeFieldFloat32(float32(v.Float())) }
+	case reflect.Float64:
+		return func(enc *JsonEncoder, v reflect.Value) { enc.writeFieldFloat64(v.Float()) }
+	case reflect.String:
+		return func(enc *JsonEncoder, v reflect.Value) { enc.writeFieldString(v.String()) }
+	case reflect.Pointer:
+		return newPointerEncoder(t)
+	case reflect.Struct:
+		return newStructEncoder(t)
+	case reflect.Map:
+		return newMapEncoder(t)
+	case reflect.Slice:
+		return newSliceEncoder(t)
+	case reflect.Array:
+		return newArrayEncoder(t)
+	default:
+		// complex, chan, func and unsafe pointer
+		return func(enc *JsonEncoder, v reflect.Value) {
+			if !v.CanInterface() {
+				enc.writeFieldString(v.String())
+				return
+			}
+			enc.writeFieldString(fmt.Sprintf("%v", v.Interface()))
+		}
+	}
+}
+
+// marshalerEncoder returns the encoder of the first marshaler interface
+// implemented by t, or nil.
+func marshalerEncoder(t reflect.Type) reflectEncoderFunc {
+	switch {
+	case t.Implements(objectMarshalerType):
+		return nilOr(func(enc *JsonEncoder, v reflect.Value) {
+			enc.writeMarshalObject(v.Interface().(ObjectMarshaler))
+		})
+	case t.Implements(arrayMarshalerType):
+		return nilOr(func(enc *JsonEncoder, v reflect.Value) {
+			enc.writeMarshalArray(v.Interface().(ArrayMarshaler))
+		})
+	case t.Implements(jsonMarshalerType):
+		return nilOr(jsonMarshalerEncoder)
+	case t.Implements(textMarshalerType):
+		return nilOr(func(enc *JsonEncoder, v reflect.Value) {
+			text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+			if err != nil {
+				enc.writeFieldString(err.Error())
+				return
+			}
+			enc.writeFieldString(string(text))
+		})
+	case t.Implements(errorType):
+		return nilOr(func(enc *JsonEncoder, v reflect.Value) { enc.writeFieldError(v.Interface().(error)) })
+	case t.Implements(stringerType):
+		return nilOr(func(enc *JsonEncoder, v reflect.Value) { enc.writeFieldString(v.Interface().(fmt.Stringer).String()) })
+	}
+	return nil
+}
+
+// nilOr writes null for the nil pointers instead of calling their methods.
+func nilOr(f reflectEncoderFunc) reflectEncoderFunc {
+	return func(enc *JsonEncoder, v reflect.Value) {
+		if v.Kind() == reflect.Pointer && v.IsNil() {
+			enc.writeFieldNil()
+			return
+		}
+		if !v.CanInterface() {
+			// unexported embedded types
+			enc.writeFieldNil()
+			return
+		}
+		f(enc, v)
+	}
+}
+
+func jsonMarshalerEncoder(enc *JsonEncoder, v reflect.Value) {
+	data, err := v.Interface().(json.Marshaler).MarshalJSON()
+	if err != nil {
+		enc.writeFieldString(err.Error())
+		return
+	}
+	if err = json.Unmarshal(data, new(json.RawMessage)); err != nil {
+		enc.writeFieldString(err.Error())
+		return
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	enc.writeJSONValue(dec)
+}
+
+// writeJSONValue encodes the next value of the valid JSON again, so that the
+// output of the json.Marshaler is redacted, colored and limited like the other
+// values and stays on a single line.
+func (enc *JsonEncoder) writeJSONValue(dec *json.Decoder) {
+	token, err := dec.Token()
+	if err != nil {
+		return
+	}
+	switch token := token.(type) {
+	case json.Delim:
+		if !enc.canNest() {
+			skipJSONValue(dec, 1)
+			return
+		}
+		if token == '{' {
+			enc.writeJSONObject(dec)
+		} else {
+			enc.writeJSONArray(dec)
+		}
+		// the closing delimiter
+		dec.Token()
+	case string:
+		enc.writeFieldString(token)
+	case json.Number:
+		enc.beginColor(enc.colors.attr.NumberColor)
+		enc.buf.AppendString(token.String())
+		enc.endColor()
+	case bool:
+		enc.writeFieldBool(token)
+	case nil:
+		enc.writeFieldNil()
+	}
+}
+
+func (enc *JsonEncoder) writeJSONObject(dec *json.Decoder) {
+	enc.writeBeginObject()
+	limit, n := enc.limits.MaxObjectKeys, 0
+	for ; dec.More(); n++ {
+		token, _ := dec.Token()
+		if limit > 0 && n >= limit {
+			skipJSONValue(dec, 0)
+			continue
+		}
+		if enc.addKey(token.(string)) {
+			enc.writeJSONValue(dec)
+		} else {
+			skipJSONValue(dec, 0)
+		}
+	}
+	if limit > 0 && n > limit {
+		enc.writeTruncatedKeys(n - limit)
+	}
+	enc.writeEndObject()
+}
+
+func (enc *JsonEncoder) writeJSONArray(dec *json.Decoder) {
+	enc.writeBeginArray()
+	limit, n := enc.limits.MaxArrayElements, 0
+	for ; dec.More(); n++ {
+		if limit > 0 && n >= limit {
+			skipJSONValue(dec, 0)
+			continue
+		}
+		if n > 0 {
+			enc.writeSplitComma()
+		}
+		enc.writeJSONValue(dec)
+	}
+	if limit > 0 && n > limit {
+		enc.writeSplitComma()
+		enc.writeTruncatedMarker(n-limit, "elements")
+	}
+	enc.writeEndArray()
+}
+
+// skipJSONValue skips the next value of the decoder, or the rest of the
+// current objects and arrays if depth > 0.
+func skipJSONValue(dec *json.Decoder, depth int) {
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			return
+		}
+		if delim, ok := token.(json.Delim); ok {
+			if delim == '{' || delim == '[' {
+				depth++
+			} else {
+				depth--
+			}
+		}
+		if depth == 0 {
+			return
+		}
+	}
+}
+
+func condAddrEncoder(canAddrEnc, elseEnc reflectEncoderFunc) reflectEncoderFunc {
+	return func(enc *JsonEncoder, v reflect.Value) {
+		if v.CanAddr() {
+			canAddrEnc(enc, v.Addr())
+		} else {
+			elseEnc(enc, v)
+		}
+	}
+}
+
+func interfaceEncoder(enc *JsonEncoder, v reflect.Value) {
+	if v.IsNil() {
+		enc.writeFieldNil()
+		return
+	}
+	enc.writeReflectValue(v.Elem())
+}
+
+func newPointerEncoder(t reflect.Type) reflectEncoderFunc {
+	elemEnc := typeEncoder(t.Elem())
+	return func(enc *JsonEncoder, v reflect.Value) {
+		if v.IsNil() {
+			enc.writeFieldNil()
+			return
+		}
+		if !enc.enterRef(visitedRef{ptr: v.UnsafePointer(), len: -1}) {
+			return
+		}
+		elemEnc(enc, v.Elem())
+		enc.leaveRef()
+	}
+}
+
+type reflectField struct {
+	name      string
+	tagged    bool
+	index     []int
+	omitEmpty bool
+	typ       reflect.Type
+}
+
+func newStructEncoder(t reflect.Type) reflectEncoderFunc {
+	fields := structFields(t)
+	encoders := make([]reflectEncoderFunc, len(fields))
+	for i := range fields {
+		encoders[i] = typeEncoder(fields[i].typ)
+	}
+	return func(enc *JsonEncoder, v reflect.Value) {
+		if !enc.canNest() {
+			return
+		}
+		enc.writeBeginObject()
+		for i := range fields {
+			fv, err := v.FieldByIndexErr(fields[i].index)
+			if err != nil {
+				// nil embedded pointer
+				continue
+			}
+			if fields[i].omitEmpty && isEmptyValue(fv) {
+				continue
+			}
+			if enc.addKey(fields[i].name) {
+				encoders[i](enc, fv)
+			}
+		}
+		enc.writeEndObject()
+	}
+}
+
+// structFields returns the encoded fields of t in the index order, the fields
+// of the untagged embedded structs are promoted following the dominance rules
+// of encoding/json.
+// See encoding/json
+func structFields(t reflect.Type) []reflectField {
+	type embeddedType struct {
+		typ   reflect.Type
+		index []int
+	}
+	var current []embeddedType
+	next := []embeddedType{{typ: t}}
+	// the number of times the types are embedded at the current and the next depth
+	var count, nextCount map[reflect.Type]int
+	visited := make(map[reflect.Type]bool)
+	var fields []reflectField
+	for len(next) > 0 {
+		current, next = next, current[:0]
+		count, nextCount = nextCount, make(map[reflect.Type]int)
+		for _, et := range current {
+			if visited[et.typ] {
+				continue
+			}
+			visited[et.typ] = true
+			for i := 0; i < et.typ.NumField(); i++ {
+				sf := et.typ.Field(i)
+				if sf.Anonymous {
+					ft := sf.Type
+					if ft.Kind() == reflect.Pointer {
+						ft = ft.Elem()
+					}
+					if !sf.IsExported() && ft.Kind() != reflect.Struct {
+						continue
+					}
+				} else if !sf.IsExported() {
+					continue
+				}
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, opts, _ := strings.Cut(tag, ",")
+				index := append(slices.Clone(et.index), i)
+				ft := sf.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Pointer {
+					ft = ft.Elem()
+				}
+				if len(name) > 0 || !sf.Anonymous || ft.Kind() != reflect.Struct {
+					field := reflectField{
+						name:      name,
+						tagged:    len(name) > 0,
+						index:     index,
+						omitEmpty: slices.Contains(strings.Split(opts, ","), "omitempty"),
+						typ:       sf.Type,
+					}
+					if len(field.name) == 0 {
+						field.name = sf.Name
+					}
+					fields = append(fields, field)
+					if count[et.typ] > 1 {
+						// the type is embedded more than once at the same depth,
+						// add a duplicate so that the field is annihilated below
+						fields = append(fields, field)
+					}
+					continue
+				}
+				nextCount[ft]++
+				if nextCount[ft] == 1 {
+					next = append(next, embeddedType{typ: ft, index: index})
+				}
+			}
+		}
+	}
+
+	// sort by name, then by depth, then by the tagged ones first
+	slices.SortFunc(fields, func(a, b reflectField) int {
+		if c := strings.Compare(a.name, b.name); c != 0 {
+			return c
+		}
+		if c := len(a.index) - len(b.index); c != 0 {
+			return c
+		}
+		if a.tagged != b.tagged {
+			if a.tagged {
+				return -1
+			}
+			return 1
+		}
+		return slices.Compare(a.index, b.index)
+	})
+	// keep the dominant field of each name, the shallowest one if it's the only
+	// one at its depth or the only tagged one at its depth
+	out := fields[:0]
+	for i, n := 0, 0; i < len(fields); i += n {
+		for n = 1; i+n < len(fields) && fields[i+n].name == fields[i].name; n++ {
+		}
+		if n == 1 || len(fields[i].index) < len(fields[i+1].index) || fields[i].tagged != fields[i+1].tagged {
+			out = append(out, fields[i])
+		}
+	}
+	fields = out
+	slices.SortFunc(fields, func(a, b reflectField) int { return slices.Compare(a.index, b.index) })
+	return fields
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
+
+func newMapEncoder(t reflect.Type) reflectEncoderFunc {
+	elemEnc := typeEncoder(t.Elem())
+	return func(enc *JsonEncoder, v reflect.Value) {
+		if v.IsNil() {
+			enc.writeFieldNil()
+			return
+		}
+		if !enc.canNest() || !enc.enterRef(mapRef(v)) {
+			return
+		}
+		defer enc.leaveRef()
+		enc.writeBeginObject()
+		n := enc.maxObjectKeys(v.Len())
+		if enc.sortMapKeys {
+			type kv struct {
+				key   string
+				value reflect.Value
+			}
+			kvs := make([]kv, 0, v.Len())
+			for iter := v.MapRange(); iter.Next(); {
+				kvs = append(kvs, kv{key: mapKeyString(iter.Key()), value: iter.Value()})
+			}
+			slices.SortFunc(kvs, func(a, b kv) int { return strings.Compare(a.key, b.key) })
+			for _, kv := range kvs[:n] {
+				if enc.addKey(kv.key) {
+					elemEnc(enc, kv.value)
+				}
+			}
+		} else {
+			i := 0
+			for iter := v.MapRange(); i < n && iter.Next(); i++ {
+				if enc.addKey(mapKeyString(iter.Key())) {
+					elemEnc(enc, iter.Value())
+				}
+			}
+		}
+		if n < v.Len() {
+			enc.writeTruncatedKeys(v.Len() - n)
+		}
+		enc.writeEndObject()
+	}
+}
+
+// mapKeyString converts the non-string map keys to strings.
+func mapKeyString(k reflect.Value) string {
+	if k.Kind() == reflect.Interface {
+		if k.IsNil() {
+			return "<nil>"
+		}
+		k = k.Elem()
+	}
+	if k.Kind() == reflect.String {
+		return k.String()
+	}
+	if k.CanInterface() {
+		if tm, ok := k.Interface().(encoding.TextMarshaler); ok {
+			if k.Kind() == reflect.Pointer && k.IsNil() {
+				return ""
+			}
+			if text, err := tm.MarshalText(); err == nil {
+				return string(text)
+			}
+		}
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(k.Float(), 'g', -1, k.Type().Bits())
+	case reflect.Bool:
+		return strconv.FormatBool(k.Bool())
+	}
+	if k.CanInterface() {
+		return fmt.Sprintf("%v", k.Interface())
+	}
+	return k.String()
+}
+
+func newSliceEncoder(t reflect.Type) reflectEncoderFunc {
+	// the byte slices are binary like encoding/json, unless the elements are
+	// marshalers
+	if t.Elem().Kind() == reflect.Uint8 {
+		p := reflect.PointerTo(t.Elem())
+		if !p.Implements(jsonMarshalerType) && !p.Implements(textMarshalerType) {
+			return func(enc *JsonEncoder, v reflect.Value) {
+				if v.IsNil() {
+					enc.writeFieldNil()
+					return
+				}
+				enc.writeFieldBinary("", v.Bytes())
+			}
+		}
+	}
+	arrayEnc := newArrayEncoder(t)
+	return func(enc *JsonEncoder, v reflect.Value) {
+		if v.IsNil() {
+			enc.writeFieldNil()
+			return
+		}
+		if !enc.enterRef(visitedRef{ptr: v.UnsafePointer(), len: v.Len()}) {
+			return
+		}
+		arrayEnc(enc, v)
+		enc.leaveRef()
+	}
+}
+
+func newArrayEncoder(t reflect.Type) reflectEncoderFunc {
+	elemEnc := typeEncoder(t.Elem())
+	return func(enc *JsonEncoder, v reflect.Value) {
+		if !enc.canNest() {
+			return
+		}
+		enc.writeBeginArray()
+		n := enc.maxArrayElements(v.Len())
+		for i := 0; i < n; i++ {
+			if i > 0 {
+				enc.writeSplitComma()
+			}
+			elemEnc(enc, v.Index(i))
+		}
+		if n < v.Len() {
+			if n > 0 {
+				enc.writeSplitComma()
+			}
+			enc.writeTruncatedMarker(v.Len()-n, "elements")
+		}
+		enc.writeEndArray()
+	}
+}
@@ -0,0 +1,93 @@
+package logx
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const _countersPerLevel = 1024
+
+type SamplingOption struct {
+	// sampling interval, default: time.Second
+	Tick time.Duration
+	// log the first N entries with the same level and message in each tick, default: 100
+	First int
+	// thereafter log every Mth entry with the same level and message in each tick,
+	// zero drops all of them. default: 0
+	Thereafter int
+}
+
+type counter struct {
+	resetAt atomic.Int64
+	counter atomic.Uint64
+}
+
+func (c *counter) incCheckReset(t int64, tick time.Duration) uint64 {
+	resetAfter := c.resetAt.Load()
+	if resetAfter > t {
+		return c.counter.Add(1)
+	}
+
+	c.counter.Store(1)
+
+	newResetAfter := t + tick.Nanoseconds()
+	if !c.resetAt.CompareAndSwap(resetAfter, newResetAfter) {
+		// We raced with another goroutine trying to reset, and it also reset
+		// the counter to 1, so we need to reincrement the counter.
+		return c.counter.Add(1)
+	}
+	return 1
+}
+
+// sampler caps the CPU and I/O load of logging while attempting to preserve a
+// representative subset of the logs. It's shared by the loggers derived from
+// the same LogContext.
+// See zap sampler
+type sampler struct {
+	option SamplingOption
+	counts [LevelPanic + 1][_countersPerLevel]counter
+}
+
+func (option SamplingOption) withDefaults() SamplingOption {
+	if option.Tick <= 0 {
+		option.Tick = time.Second
+	}
+	if option.First <= 0 {
+		option.First = 100
+	}
+	if option.Thereafter < 0 {
+		option.Thereafter = 0
+	}
+	return option
+}
+
+func newSampler(option SamplingOption) *sampler {
+	return &sampler{option: option.withDefaults()}
+}
+
+// check reports whether the entry should be written.
+func (s *sampler) check(level LevelType, msg string, now time.Time) bool {
+	if level > LevelPanic {
+		return true
+	}
+	c := &s.counts[level][fnv32a(msg)%_countersPerLevel]
+	n := c.incCheckReset(now.UnixNano(), s.option.Tick)
+	if n <= uint64(s.option.First) {
+		return true
+	}
+	return s.option.Thereafter > 0 && (n-uint64(s.option.First))%uint64(s.option.Thereafter) == 0
+}
+
+// fnv32a, adapted from "hash/fnv", but without a []byte(string) alloc
+func fnv32a(s string) uint32 {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+	hash := uint32(offset32)
+	for i := 0; i < len(s); i++ {
+		hash ^= uint32(s[i])
+		hash *= prime32
+	}
+	return hash
+}
@@ -0,0 +1,108 @@
+package logx
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+var stacktracePool = sync.Pool{New: func() any { return &stacktrace{pcs: make([]uintptr, 64)} }}
+
+type StacktraceOption struct {
+	// stacktrace key, default: "stacktrace"
+	StacktraceKey string
+	// the entries at or above the level carry the stacktrace, default: LevelTrace
+	Level LevelType
+}
+
+type stacktraceField struct {
+	option StacktraceOption
+	enable bool
+}
+
+// stacktrace is the program counters of the goroutine captured by a log call.
+type stacktrace struct {
+	pcs    []uintptr
+	frames int
+	// start at the frame which panicked instead of the first frame
+	panicked bool
+}
+
+// captureStacktrace captures the stack of the calling goroutine, skipping the
+// skip frames above runtime.Callers.
+func captureStacktrace(skip int) *stacktrace {
+	st := stacktracePool.Get().(*stacktrace)
+	for {
+		st.frames = runtime.Callers(skip+1, st.pcs)
+		if st.frames < len(st.pcs) {
+			st.panicked = false
+			return st
+		}
+		st.pcs = make([]uintptr, len(st.pcs)*2)
+	}
+}
+
+func (st *stacktrace) free() {
+	stacktracePool.Put(st)
+}
+
+// appendTo appends the stacktrace in the format of the panics, each frame
+// is written as the function followed by the file and line on the next line.
+func (st *stacktrace) appendTo(buf *Buffer) {
+	frames := runtime.CallersFrames(st.pcs[:st.frames])
+	first := true
+	// skip the deferred calls and the runtime frames raising the panic
+	skipping, panicking := st.panicked, false
+	for {
+		frame, more := frames.Next()
+		if skipping {
+			if frame.Function == "runtime.gopanic" {
+				panicking = true
+			} else if panicking && !strings.HasPrefix(frame.Function, "runtime.") {
+				skipping = false
+			}
+		}
+		if !skipping && frame.Function != "runtime.goexit" {
+			if !first {
+				buf.AppendByte('\n')
+			}
+			first = false
+			buf.AppendString(frame.Function)
+			buf.AppendString("\n\t")
+			buf.AppendString(frame.File)
+			buf.AppendByte(':')
+			buf.AppendInt(int64(frame.Line))
+		}
+		if !more {
+			return
+		}
+	}
+}
+
+func (enc *JsonEncoder) writeStacktrace(st *stacktrace) {
+	buf := bufPool.Get().(*Buffer)
+	buf.Reset()
+	st.appendTo(buf)
+	key := enc.stackF.option.StacktraceKey
+	if len(key) == 0 {
+		key = "stacktrace"
+	}
+	enc.writeKey(key)
+	// the stacktrace always has the newlines and tabs to escape
+	enc.writeQuote()
+	enc.beginColor(enc.colors.attr.StringColor)
+	appendQuoteString(enc.buf, unsafe.String(unsafe.SliceData(buf.Bytes()), buf.Len()))
+	enc.endColor()
+	enc.writeQuote()
+	bufPool.Put(buf)
+}
+
+// appendStacktrace appends the stacktrace on the lines following the entry.
+func appendStacktrace(buf *Buffer, st *stacktrace) {
+	if st == nil {
+		return
+	}
+	buf.AppendByte('\n')
+	st.appendTo(buf)
+}
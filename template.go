@@ -0,0 +1,235 @@
+package logx
+
+import (
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+const defaultTemplateKey = "msg_template"
+
+// maxCachedTemplates bounds the cache of the parsed templates, the templates
+// are expected to be constants, the others are parsed on every call.
+const maxCachedTemplates = 4096
+
+var (
+	templateCache  sync.Map
+	cachedTemplate atomic.Int64
+)
+
+// msgTemplate is a parsed message template like "user {user} logged in", the
+// "{{" and "}}" are the escaped braces.
+type msgTemplate struct {
+	parts []templatePart
+}
+
+type templatePart struct {
+	// the literal text or the key of the placeholder
+	text        string
+	placeholder bool
+}
+
+func parseTemplate(template string) *msgTemplate {
+	if t, ok := templateCache.Load(template); ok {
+		return t.(*msgTemplate)
+	}
+	t := new(msgTemplate)
+	var text []byte
+	flush := func() {
+		if len(text) > 0 {
+			t.parts = append(t.parts, templatePart{text: string(text)})
+			text = text[:0]
+		}
+	}
+	for i := 0; i < len(template); {
+		c := template[i]
+		if (c == '{' || c == '}') && i+1 < len(template) && template[i+1] == c {
+			text = append(text, c)
+			i += 2
+			continue
+		}
+		if c == '{' {
+			end := strings.IndexAny(template[i+1:], "{}")
+			if end > 0 && template[i+1+end] == '}' {
+				flush()
+				t.parts = append(t.parts, templatePart{text: template[i+1 : i+1+end], placeholder: true})
+				i += end + 2
+				continue
+			}
+		}
+		text = append(text, c)
+		i++
+	}
+	flush()
+	if cachedTemplate.Load() < maxCachedTemplates {
+		if actual, loaded := templateCache.LoadOrStore(template, t); loaded {
+			return actual.(*msgTemplate)
+		}
+		cachedTemplate.Add(1)
+	}
+	return t
+}
+
+func (l *LoggerX) Tracet(template string, fields ...Field) {
+	l.printTemplate(LevelTrace, template, fields, false)
+}
+
+func (l *LoggerX) Debugt(template string, fields ...Field) {
+	l.printTemplate(LevelDebug, template, fields, false)
+}
+
+// Infot writes the msg rendered from the template by replacing the placeholders
+// like {user} with the values of the fields with the same keys, the fields of
+// the log call are looked up first and then the fields of the logger. The fields
+// are written as usual and the template is written under the key "msg_template".
+// The placeholders without fields are kept as is.
+func (l *LoggerX) Infot(template string, fields ...Field) {
+	l.printTemplate(LevelInfo, template, fields, false)
+}
+
+func (l *LoggerX) Warnt(template string, fields ...Field) {
+	l.printTemplate(LevelWarn, template, fields, false)
+}
+
+func (l *LoggerX) Errort(template string, fields ...Field) {
+	l.printTemplate(LevelError, template, fields, false)
+}
+
+// DPanict panics with the rendered msg after the entry is written in the
+// development mode.
+func (l *LoggerX) DPanict(template string, fields ...Field) {
+	development := l.context().development
+	msg := l.printTemplate(LevelDPanic, template, fields, development)
+	if development {
+		panic(msg)
+	}
+}
+
+func (l *LoggerX) Fatalt(template string, fields ...Field) {
+	l.printTemplate(LevelFatal, template, fields, false)
+	os.Exit(1)
+}
+
+// Panict panics with the rendered msg after the entry is written.
+func (l *LoggerX) Panict(template string, fields ...Field) {
+	panic(l.printTemplate(LevelPanic, template, fields, true))
+}
+
+// printTemplate is the print of the templates, the entries are sampled by the
+// template instead of the rendered msg. If panics, the msg is rendered even if
+// the entry is discarded and returned for the panic.
+func (l *LoggerX) printTemplate(level LevelType, template string, fields []Field, panics bool) (msg string) {
+	lc := l.context()
+	enc := lc.jsonEncoder()
+	if enc == nil && panics {
+		enc = &JsonEncoder{LogContext: lc}
+	}
+	// discard the log
+	enabled := enc != nil && lc.writer != nil && lc.writer != io.Discard && lc.levelT <= level
+	now := time.Now()
+	if enabled && lc.sampler != nil && !lc.sampler.check(level, template, now) {
+		enabled = false
+	}
+	if !enabled && !panics {
+		return
+	}
+	if enabled {
+		fields = evalLazyFields(fields)
+	}
+	buf := enc.renderTemplate(parseTemplate(template), fields)
+	msg = unsafe.String(unsafe.SliceData(buf.Bytes()), buf.Len())
+	if enabled {
+		key := lc.templateKey
+		if len(key) == 0 {
+			key = defaultTemplateKey
+		}
+		// don't append to the caller's slice
+		fields = append(fields[:len(fields):len(fields)], String(key, template))
+		l.output(lc, entry{level: level, message: msg, time: now}, fields)
+	}
+	if panics {
+		msg = string(buf.Bytes())
+	}
+	bufPool.Put(buf)
+	return
+}
+
+// evalLazyFields returns the fields with the Lazy and LazyObject fields replaced
+// by their values, so that the msg and the written fields get the same values.
+// The fields are copied if any is replaced.
+func evalLazyFields(fields []Field) []Field {
+	copied := false
+	for i := range fields {
+		var field Field
+		switch fields[i].Type {
+		case LazyType:
+			field = Field{Key: fields[i].Key, Type: AnyType, AnyValue: fields[i].AnyValue.(func() any)()}
+		case LazyObjectType:
+			field = Object(fields[i].Key, fields[i].AnyValue.(func() []Field)()...)
+		default:
+			continue
+		}
+		if !copied {
+			fields, copied = slices.Clone(fields), true
+		}
+		fields[i] = field
+	}
+	return fields
+}
+
+// renderTemplate renders the msg of the template into a pooled buffer, the
+// values are written without the colors, the escapes and the quotes of the
+// strings, which are written by the msg itself.
+func (enc *JsonEncoder) renderTemplate(t *msgTemplate, fields []Field) *Buffer {
+	nenc := enc.clone()
+	nenc.plain = true
+	nenc.depth = 1
+	for _, part := range t.parts {
+		if !part.placeholder {
+			nenc.buf.AppendString(part.text)
+			continue
+		}
+		field := nenc.findField(fields, part.text)
+		switch {
+		case field == nil:
+			nenc.buf.AppendByte('{')
+			nenc.buf.AppendString(part.text)
+			nenc.buf.AppendByte('}')
+		case nenc.redactor != nil && nenc.redactor.matchKey(field.Key):
+			nenc.buf.AppendString(nenc.redactor.replacement)
+		case field.Type == StringType:
+			nenc.buf.AppendString(field.StringValue)
+		default:
+			start := nenc.buf.Len()
+			nenc.writeFieldValue(field)
+			if bs := nenc.buf.Bytes()[start:]; len(bs) >= 2 && bs[0] == '"' && bs[len(bs)-1] == '"' {
+				copy(bs, bs[1:len(bs)-1])
+				nenc.buf.Truncate(start + len(bs) - 2)
+			}
+		}
+	}
+	buf := nenc.buf
+	putJsonEncoder(nenc)
+	return buf
+}
+
+// findField returns the field with the key of the fields, or the last one of
+// the preFields.
+func (enc *JsonEncoder) findField(fields []Field, key string) *Field {
+	for i := range fields {
+		if fields[i].Key == key && fields[i].Type != NamespaceType {
+			return &fields[i]
+		}
+	}
+	for i := len(enc.preFields) - 1; i >= 0; i-- {
+		if enc.preFields[i].Key == key && enc.preFields[i].Type != NamespaceType {
+			return &enc.preFields[i]
+		}
+	}
+	return nil
+}
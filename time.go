@@ -2,51 +2,210 @@ package logx
 
 import "time"
 
+type TimeEncoding uint8
+
+const (
+	// format the time with the layout
+	TimeLayout TimeEncoding = iota
+	// format the time with time.RFC3339
+	TimeRFC3339
+	// format the time with time.RFC3339Nano
+	TimeRFC3339Nano
+	// the number of seconds since the Unix epoch
+	TimeEpochSeconds
+	// the number of milliseconds since the Unix epoch
+	TimeEpochMillis
+	// the number of microseconds since the Unix epoch
+	TimeEpochMicros
+	// the number of nanoseconds since the Unix epoch
+	TimeEpochNanos
+)
+
+type DurationEncoding uint8
+
+const (
+	// format the duration with time.Duration.String, e.g. "1.5s"
+	DurationString DurationEncoding = iota
+	// the integer number of nanoseconds
+	DurationNanos
+	// the integer number of milliseconds
+	DurationMillis
+	// the float number of seconds
+	DurationSeconds
+)
+
 type TimeOption struct {
 	// time key, default: time
 	TimeKey string
-	// convert time to int64 timestamp with UnixNano, default: false
+	// convert time to int64 timestamp with UnixNano, same as TimeEpochNanos, default: false
 	Timestamp bool
 	// time layout, default: time.DateTime
 	Layout string
+	// time encoding, default: TimeLayout
+	Encoding TimeEncoding
+	// encode the epoch time as a float number with the fractional part, default: false
+	Float bool
+	// convert time to UTC before formatting, default: false
+	UTC bool
+	// convert time to the location before formatting, takes precedence over UTC, default: nil
+	Location *time.Location
+}
+
+// FieldTimeOption is the encoding of the Time fields, which is independent of
+// the entry time once set with WithFieldTime.
+type FieldTimeOption struct {
+	// time layout, default: time.DateTime
+	Layout string
+	// time encoding, default: TimeLayout
+	Encoding TimeEncoding
+	// encode the epoch time as a float number with the fractional part, default: false
+	Float bool
+	// convert time to UTC before formatting, default: false
+	UTC bool
+	// convert time to the location before formatting, takes precedence over UTC, default: nil
+	Location *time.Location
+}
+
+func (o *TimeOption) fieldTimeOption() FieldTimeOption {
+	option := FieldTimeOption{
+		Layout:   o.Layout,
+		Encoding: o.Encoding,
+		Float:    o.Float,
+		UTC:      o.UTC,
+		Location: o.Location,
+	}
+	if o.Timestamp && option.Encoding == TimeLayout {
+		option.Encoding = TimeEpochNanos
+	}
+	return option
+}
+
+// timeFormat is the resolved FieldTimeOption.
+type timeFormat struct {
+	encoding TimeEncoding
+	layout   string
+	float    bool
+	location *time.Location
+}
+
+func newTimeFormat(option FieldTimeOption) timeFormat {
+	f := timeFormat{encoding: option.Encoding, layout: option.Layout, float: option.Float, location: option.Location}
+	switch f.encoding {
+	case TimeRFC3339:
+		f.layout = time.RFC3339
+	case TimeRFC3339Nano:
+		f.layout = time.RFC3339Nano
+	}
+	if len(f.layout) == 0 {
+		f.layout = time.DateTime
+	}
+	if f.location == nil && option.UTC {
+		f.location = time.UTC
+	}
+	return f
+}
+
+func (f *timeFormat) numeric() bool { return f.encoding >= TimeEpochSeconds }
+
+func (f *timeFormat) appendTime(buf *Buffer, ti time.Time) {
+	var unit int64
+	switch f.encoding {
+	case TimeEpochSeconds:
+		unit = int64(time.Second)
+	case TimeEpochMillis:
+		unit = int64(time.Millisecond)
+	case TimeEpochMicros:
+		unit = int64(time.Microsecond)
+	case TimeEpochNanos:
+		buf.AppendInt(ti.UnixNano())
+		return
+	default:
+		if f.location != nil {
+			ti = ti.In(f.location)
+		}
+		buf.AppendTime(ti, f.layout)
+		return
+	}
+	if f.float {
+		appendEpochFloat(buf, ti.UnixNano(), unit)
+	} else {
+		buf.AppendInt(ti.UnixNano() / unit)
+	}
+}
+
+// appendEpochFloat appends nanos/unit as a decimal number with all the digits
+// of the fraction, which keeps the nanosecond precision lost by float64.
+func appendEpochFloat(buf *Buffer, nanos, unit int64) {
+	u := uint64(nanos)
+	if nanos < 0 {
+		buf.AppendByte('-')
+		u = -u
+	}
+	buf.AppendUint(u / uint64(unit))
+	buf.AppendByte('.')
+	frac := u % uint64(unit)
+	for d := uint64(unit) / 10; d > 1 && frac < d; d /= 10 {
+		buf.AppendByte('0')
+	}
+	buf.AppendUint(frac)
 }
 
 type timeField struct {
 	option      TimeOption
+	fieldOption FieldTimeOption
+	// encode the Time fields with fieldOption instead of option
+	fieldEnable bool
 	enable      bool
 	color       bool
 	stringColor ColorAttr
 	numberColor ColorAttr
+	entryFormat timeFormat
+	fieldFormat timeFormat
+}
+
+func (t *timeField) init() {
+	t.entryFormat = newTimeFormat(t.option.fieldTimeOption())
+	if t.fieldEnable {
+		t.fieldFormat = newTimeFormat(t.fieldOption)
+	} else {
+		t.fieldFormat = t.entryFormat
+	}
 }
 
 func (t *timeField) AppendField(enc *JsonEncoder, ti time.Time) {
-	enc.writeFieldKey(t.option.TimeKey)
-	enc.writeSplitColon()
-	t.AppendTime(enc, ti)
+	enc.writeKey(t.option.TimeKey)
+	t.appendTime(enc, &t.entryFormat, ti)
 }
 
+// AppendTime appends the value of a Time field.
 func (t *timeField) AppendTime(enc *JsonEncoder, ti time.Time) {
-	if !t.option.Timestamp {
+	t.appendTime(enc, &t.fieldFormat, ti)
+}
+
+func (t *timeField) appendTime(enc *JsonEncoder, f *timeFormat, ti time.Time) {
+	if !f.numeric() {
 		enc.writeQuote()
 	}
-	t.AppendTimePrimitive(enc.buf, ti)
-	if !t.option.Timestamp {
+	t.appendTimePrimitive(enc.buf, f, ti)
+	if !f.numeric() {
 		enc.writeQuote()
 	}
 }
 
 func (t *timeField) AppendTimePrimitive(buf *Buffer, ti time.Time) {
+	t.appendTimePrimitive(buf, &t.entryFormat, ti)
+}
+
+func (t *timeField) appendTimePrimitive(buf *Buffer, f *timeFormat, ti time.Time) {
 	if t.color {
-		if t.option.Timestamp {
-			appendColorWithFunc(buf, t.numberColor, func(buf *Buffer) { buf.AppendInt(ti.UnixNano()) })
+		if f.numeric() {
+			appendColorBegin(buf, t.numberColor)
 		} else {
-			appendColorWithFunc(buf, t.stringColor, func(buf *Buffer) { buf.AppendTime(ti, t.option.Layout) })
+			appendColorBegin(buf, t.stringColor)
 		}
-		return
 	}
-	if t.option.Timestamp {
-		buf.AppendInt(ti.UnixNano())
-		return
+	f.appendTime(buf, ti)
+	if t.color {
+		appendColorEnd(buf)
 	}
-	buf.AppendTime(ti, t.option.Layout)
 }
@@ -0,0 +1,182 @@
+package logx
+
+import (
+	"reflect"
+	"time"
+	"unsafe"
+)
+
+// Primitive is the set of the types with a concrete FieldType, the named types
+// are encoded by their underlying types except time.Duration.
+type Primitive interface {
+	~string | ~bool |
+		~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 |
+		~float32 | ~float64 |
+		time.Time
+}
+
+// F returns the field of the concrete FieldType of T without boxing the value.
+func F[T Primitive](key string, value T) Field {
+	typ, p := reflect.TypeFor[T](), unsafe.Pointer(&value)
+	switch typ {
+	case timeType:
+		return Time(key, *(*time.Time)(p))
+	case durationType:
+		return Duration(key, *(*time.Duration)(p))
+	}
+	switch typ.Kind() {
+	case reflect.String:
+		return String(key, *(*string)(p))
+	case reflect.Bool:
+		return Bool(key, *(*bool)(p))
+	case reflect.Int:
+		return Int(key, *(*int)(p))
+	case reflect.Int8:
+		return Int8(key, *(*int8)(p))
+	case reflect.Int16:
+		return Int16(key, *(*int16)(p))
+	case reflect.Int32:
+		return Int32(key, *(*int32)(p))
+	case reflect.Int64:
+		return Int64(key, *(*int64)(p))
+	case reflect.Uint:
+		return UInt(key, *(*uint)(p))
+	case reflect.Uint8:
+		return UInt8(key, *(*uint8)(p))
+	case reflect.Uint16:
+		return UInt16(key, *(*uint16)(p))
+	case reflect.Uint32:
+		return UInt32(key, *(*uint32)(p))
+	case reflect.Uint64:
+		return UInt64(key, *(*uint64)(p))
+	case reflect.Float32:
+		return Float32(key, *(*float32)(p))
+	default:
+		// the kinds are exhausted by Primitive
+		return Float64(key, *(*float64)(p))
+	}
+}
+
+// Slice returns the typed slice field of T, the slice is not copied and must
+// not be modified until the entry is written.
+func Slice[T Primitive](key string, value []T) Field {
+	typ, p := reflect.TypeFor[T](), unsafe.Pointer(&value)
+	switch typ {
+	case timeType:
+		return Times(key, *(*[]time.Time)(p))
+	case durationType:
+		return Durations(key, *(*[]time.Duration)(p))
+	}
+	switch typ.Kind() {
+	case reflect.String:
+		return Strings(key, *(*[]string)(p))
+	case reflect.Bool:
+		return Bools(key, *(*[]bool)(p))
+	case reflect.Int:
+		return Ints(key, *(*[]int)(p))
+	case reflect.Int8:
+		return Int8s(key, *(*[]int8)(p))
+	case reflect.Int16:
+		return Int16s(key, *(*[]int16)(p))
+	case reflect.Int32:
+		return Int32s(key, *(*[]int32)(p))
+	case reflect.Int64:
+		return Int64s(key, *(*[]int64)(p))
+	case reflect.Uint:
+		return UInts(key, *(*[]uint)(p))
+	case reflect.Uint8:
+		return UInt8s(key, *(*[]uint8)(p))
+	case reflect.Uint16:
+		return UInt16s(key, *(*[]uint16)(p))
+	case reflect.Uint32:
+		return UInt32s(key, *(*[]uint32)(p))
+	case reflect.Uint64:
+		return UInt64s(key, *(*[]uint64)(p))
+	case reflect.Float32:
+		return Float32s(key, *(*[]float32)(p))
+	default:
+		// the kinds are exhausted by Primitive
+		return Float64s(key, *(*[]float64)(p))
+	}
+}
+
+// sliceField stores the data pointer and the length of the slice instead of
+// boxing the slice header.
+func sliceField[T any](key string, typ FieldType, value []T) Field {
+	return Field{Key: key, Type: typ, AnyValue: unsafe.Pointer(unsafe.SliceData(value)), IntValue: int64(len(value))}
+}
+
+func fieldSlice[T any](field *Field) []T {
+	return unsafe.Slice((*T)(field.AnyValue.(unsafe.Pointer)), field.IntValue)
+}
+
+func Strings(key string, value []string) Field { return sliceField(key, StringsType, value) }
+
+func Bools(key string, value []bool) Field { return sliceField(key, BoolsType, value) }
+
+func Ints(key string, value []int) Field { return sliceField(key, IntsType, value) }
+
+func Int8s(key string, value []int8) Field { return sliceField(key, Int8sType, value) }
+
+func Int16s(key string, value []int16) Field { return sliceField(key, Int16sType, value) }
+
+func Int32s(key string, value []int32) Field { return sliceField(key, Int32sType, value) }
+
+func Int64s(key string, value []int64) Field { return sliceField(key, Int64sType, value) }
+
+func UInts(key string, value []uint) Field { return sliceField(key, UintsType, value) }
+
+func UInt8s(key string, value []uint8) Field { return sliceField(key, Uint8sType, value) }
+
+func UInt16s(key string, value []uint16) Field { return sliceField(key, Uint16sType, value) }
+
+func UInt32s(key string, value []uint32) Field { return sliceField(key, Uint32sType, value) }
+
+func UInt64s(key string, value []uint64) Field { return sliceField(key, Uint64sType, value) }
+
+func Float32s(key string, value []float32) Field { return sliceField(key, Float32sType, value) }
+
+func Float64s(key string, value []float64) Field { return sliceField(key, Float64sType, value) }
+
+func Times(key string, value []time.Time) Field { return sliceField(key, TimesType, value) }
+
+func Durations(key string, value []time.Duration) Field { return sliceField(key, DurationsType, value) }
+
+// writeFieldSlice writes the typed slice fields.
+func (enc *JsonEncoder) writeFieldSlice(field *Field) {
+	switch field.Type {
+	case StringsType:
+		writeFieldArrayListFor(enc, fieldSlice[string](field), (*JsonEncoder).writeFieldString)
+	case BoolsType:
+		writeFieldArrayListFor(enc, fieldSlice[bool](field), (*JsonEncoder).writeFieldBool)
+	case IntsType:
+		writeFieldArrayListFor(enc, fieldSlice[int](field), (*JsonEncoder).writeFieldInt)
+	case Int8sType:
+		writeFieldArrayListFor(enc, fieldSlice[int8](field), (*JsonEncoder).writeFieldInt8)
+	case Int16sType:
+		writeFieldArrayListFor(enc, fieldSlice[int16](field), (*JsonEncoder).writeFieldInt16)
+	case Int32sType:
+		writeFieldArrayListFor(enc, fieldSlice[int32](field), (*JsonEncoder).writeFieldInt32)
+	case Int64sType:
+		writeFieldArrayListFor(enc, fieldSlice[int64](field), (*JsonEncoder).writeFieldInt64)
+	case UintsType:
+		writeFieldArrayListFor(enc, fieldSlice[uint](field), (*JsonEncoder).writeFieldUint)
+	case Uint8sType:
+		writeFieldArrayListFor(enc, fieldSlice[uint8](field), (*JsonEncoder).writeFieldUint8)
+	case Uint16sType:
+		writeFieldArrayListFor(enc, fieldSlice[uint16](field), (*JsonEncoder).writeFieldUint16)
+	case Uint32sType:
+		writeFieldArrayListFor(enc, fieldSlice[uint32](field), (*JsonEncoder).writeFieldUint32)
+	case Uint64sType:
+		writeFieldArrayListFor(enc, fieldSlice[uint64](field), (*JsonEncoder).writeFieldUint64)
+	case Float32sType:
+		writeFieldArrayListFor(enc, fieldSlice[float32](field), (*JsonEncoder).writeFieldFloat32)
+	case Float64sType:
+		writeFieldArrayListFor(enc, fieldSlice[float64](field), (*JsonEncoder).writeFieldFloat64)
+	case TimesType:
+		writeFieldArrayListFor(enc, fieldSlice[time.Time](field), (*JsonEncoder).writeFieldTime)
+	case DurationsType:
+		writeFieldArrayListFor(enc, fieldSlice[time.Duration](field), (*JsonEncoder).writeFieldDuration)
+	}
+}
@@ -0,0 +1,205 @@
+package logx
+
+import (
+	"bytes"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// liveConfig is shared by all the LogContext copies attached to a ConfigWatcher.
+// Each built logger compares the generation of its snapshot with the latest one
+// and swaps in a reconfigured snapshot when they differ.
+type liveConfig struct {
+	state atomic.Pointer[liveState]
+}
+
+type liveState struct {
+	gen    uint64
+	cfg    *Config
+	writer WriteSyncer
+	// shared by all the snapshots of the generation, and by the following
+	// generations with the same sampling options
+	sampler *sampler
+}
+
+// ConfigWatcher polls a JSON config file and hot reloads the loggers built from
+// the LogContext it is attached to. It doesn't depend on file system events, so
+// it works for files replaced by editors, symlink swaps and mounted volumes.
+type ConfigWatcher struct {
+	path     string
+	interval time.Duration
+	live     *liveConfig
+	logger   Logger
+	outputs  outputSet
+	// the files dropped from the outputs, closed after the grace period
+	retired []retiredFile
+
+	modTime time.Time
+	size    int64
+	data    []byte
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// retiredFile is a file dropped from the outputs at the time.
+type retiredFile struct {
+	file *os.File
+	at   time.Time
+}
+
+// The dropped outputs are kept open for at least retirePolls intervals and
+// minRetireGrace, so that the writers which loaded the old settings can finish.
+const (
+	minRetireGrace = 5 * time.Second
+	retirePolls    = 3
+)
+
+// WatchConfig loads the JSON config file at path and starts polling the file
+// every interval, default: time.Second. Level, sampling, caller, time and
+// outputs of the loggers built from lc, before or after the call, are updated
+// atomically on change: each entry is written with either the old or the new
+// settings. Each config is applied over the context the logger was built from,
+// so the settings removed from the file fall back to those of the context.
+// Invalid configs are reported through a logger built from lc and the last good
+// config is kept.
+//
+// The files dropped from the outputs are closed after a grace period of at
+// least retirePolls intervals and minRetireGrace, the entries of a goroutine
+// which loaded the old settings but is delayed beyond it before writing are
+// lost.
+func (lc *LogContext) WatchConfig(path string, interval time.Duration) (*ConfigWatcher, error) {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	w := &ConfigWatcher{
+		path:     path,
+		interval: interval,
+		live:     new(liveConfig),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if _, err := w.changed(); err != nil {
+		return nil, err
+	}
+	cfg, err := ParseConfig(w.data)
+	if err != nil {
+		return nil, err
+	}
+	writer, err := w.openOutputs(cfg)
+	if err != nil {
+		w.outputs.close()
+		return nil, err
+	}
+	w.live.state.Store(&liveState{gen: 1, cfg: cfg, writer: writer, sampler: configSampler(cfg.Sampling, lc.sampler)})
+	lc.live = w.live
+	w.logger = lc.Build()
+
+	go w.run()
+	return w, nil
+}
+
+// Stop stops polling and closes the files opened for the outputs.
+func (w *ConfigWatcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stop)
+		<-w.done
+		w.closeRetired(time.Time{})
+		w.outputs.close()
+	})
+}
+
+func (w *ConfigWatcher) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case now := <-ticker.C:
+			w.closeRetired(now.Add(-max(retirePolls*w.interval, minRetireGrace)))
+			w.reload()
+		}
+	}
+}
+
+// closeRetired closes the files retired before the time, or all if it's zero.
+func (w *ConfigWatcher) closeRetired(before time.Time) {
+	var files []*os.File
+	n := 0
+	for _, r := range w.retired {
+		if before.IsZero() || r.at.Before(before) {
+			files = append(files, r.file)
+		} else {
+			w.retired[n] = r
+			n++
+		}
+	}
+	w.retired = w.retired[:n]
+	closeFiles(files)
+}
+
+func (w *ConfigWatcher) reload() {
+	changed, err := w.changed()
+	if err != nil {
+		w.logger.Error("logx: failed to read config", String("path", w.path), Error("error", err))
+		return
+	}
+	if !changed {
+		return
+	}
+	cfg, err := ParseConfig(w.data)
+	if err != nil {
+		w.logger.Error("logx: failed to reload config", String("path", w.path), Error("error", err))
+		return
+	}
+	writer, err := w.openOutputs(cfg)
+	if err != nil {
+		w.logger.Error("logx: failed to open outputs", String("path", w.path), Error("error", err))
+		return
+	}
+	old := w.live.state.Load()
+	w.live.state.Store(&liveState{
+		gen:     old.gen + 1,
+		cfg:     cfg,
+		writer:  writer,
+		sampler: configSampler(cfg.Sampling, old.sampler),
+	})
+	now := time.Now()
+	for _, file := range w.outputs.release(cfg.Outputs) {
+		w.retired = append(w.retired, retiredFile{file: file, at: now})
+	}
+}
+
+// changed reports whether the content of the config file has changed since the
+// last call, the content is kept in w.data.
+func (w *ConfigWatcher) changed() (bool, error) {
+	fi, err := os.Stat(w.path)
+	if err != nil {
+		return false, err
+	}
+	if w.data != nil && fi.ModTime().Equal(w.modTime) && fi.Size() == w.size {
+		return false, nil
+	}
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		return false, err
+	}
+	w.modTime, w.size = fi.ModTime(), fi.Size()
+	if w.data != nil && bytes.Equal(data, w.data) {
+		return false, nil
+	}
+	w.data = data
+	return true, nil
+}
+
+func (w *ConfigWatcher) openOutputs(cfg *Config) (WriteSyncer, error) {
+	if len(cfg.Outputs) == 0 {
+		return nil, nil
+	}
+	return w.outputs.open(cfg.Outputs)
+}